@@ -0,0 +1,424 @@
+package gols
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// archiveEntry is a file or directory inside an archive. It serves as
+// both the os.DirEntry and the os.FileInfo of the entry, and as the Sys
+// value of the latter, so printers can tell it from files on disk.
+type archiveEntry struct {
+	name       string
+	size       int64
+	mode       os.FileMode
+	modTime    time.Time
+	owner      string
+	group      string
+	linkTarget string
+}
+
+func (e *archiveEntry) Name() string               { return e.name }
+func (e *archiveEntry) IsDir() bool                { return e.mode.IsDir() }
+func (e *archiveEntry) Type() os.FileMode          { return e.mode.Type() }
+func (e *archiveEntry) Info() (os.FileInfo, error) { return e, nil }
+func (e *archiveEntry) Size() int64                { return e.size }
+func (e *archiveEntry) Mode() os.FileMode          { return e.mode }
+func (e *archiveEntry) ModTime() time.Time         { return e.modTime }
+func (e *archiveEntry) Sys() any                   { return e }
+
+// archive is the table of contents of an archive file, read once. Paths
+// are slash-separated and relative to the archive root, which is ".".
+type archive struct {
+	entries  map[string]*archiveEntry
+	children map[string][]os.DirEntry
+}
+
+// Decompressor returns a reader of the decompressed contents of r, the
+// compressed stream of a tar archive. Closing it releases what it holds,
+// but not r, and reports a corrupt stream that was read to the end.
+type Decompressor func(r io.Reader) (io.ReadCloser, error)
+
+var (
+	decompressorsMu sync.RWMutex
+	decompressors   = map[string]Decompressor{
+		"gzip": func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+		"bzip2": func(r io.Reader) (io.ReadCloser, error) {
+			return io.NopCloser(bzip2.NewReader(r)), nil
+		},
+	}
+)
+
+// tarCompressions names the compression of each compressed tar format,
+// as given to RegisterDecompressor.
+var tarCompressions = map[string]string{
+	"tar.gz":  "gzip",
+	"tar.bz2": "bzip2",
+	"tar.xz":  "xz",
+	"tar.zst": "zstd",
+}
+
+// RegisterDecompressor makes the tar archives compressed with
+// compression, "xz" or "zstd", readable with d. gzip and bzip2 are
+// built in; the standard library has no decoder for the other two, so
+// without a decompressor those archives fail to open with an
+// unsupported compression error. Like RegisterRenderer, it panics when
+// compression is taken or d is nil.
+func RegisterDecompressor(compression string, d Decompressor) {
+	decompressorsMu.Lock()
+	defer decompressorsMu.Unlock()
+	if d == nil {
+		panic("gols: RegisterDecompressor of nil decompressor " + compression)
+	}
+	if _, found := decompressors[compression]; found {
+		panic("gols: RegisterDecompressor called twice for " + compression)
+	}
+	decompressors[compression] = d
+}
+
+// archiveFormat returns the format of an archive file by its name, or ""
+// for other files.
+func archiveFormat(name string) string {
+	name = strings.ToLower(name)
+	for _, format := range []struct{ suffix, format string }{
+		{".zip", "zip"}, {".jar", "zip"},
+		{".tar", "tar"},
+		{".tar.gz", "tar.gz"}, {".tgz", "tar.gz"},
+		{".tar.bz2", "tar.bz2"}, {".tbz2", "tar.bz2"},
+		{".tar.xz", "tar.xz"}, {".txz", "tar.xz"},
+		{".tar.zst", "tar.zst"}, {".tzst", "tar.zst"},
+	} {
+		if strings.HasSuffix(name, format.suffix) {
+			return format.format
+		}
+	}
+	return ""
+}
+
+// openArchive makes the archive containing name available to statPath
+// and readDir when name is an archive file, like release.zip, or a path
+// inside one, like release.zip/bin. It does nothing for other paths,
+// and when listing an Options.FS. An archive file that cannot be read
+// is left to be listed as a file, while paths inside it fail. Archives
+// stay open for the rest of the call.
+func (ls *lister) openArchive(name string) error {
+	if ls.fsys != nil {
+		return nil
+	}
+	name = filepath.Clean(name)
+	if info, err := os.Stat(name); err == nil && !info.Mode().IsRegular() {
+		return nil
+	}
+
+	for prefix := name; ; {
+		if archiveFormat(prefix) != "" {
+			if info, err := os.Stat(prefix); err == nil && info.Mode().IsRegular() {
+				ls.cache.archivesMu.Lock()
+				defer ls.cache.archivesMu.Unlock()
+				if _, found := ls.cache.archives[prefix]; found {
+					return nil
+				}
+				a, err := readArchive(prefix, info)
+				switch {
+				case err != nil && prefix == name:
+					// Only its name makes it an archive, so it is
+					// listed as the plain file it is.
+					return nil
+				case err != nil:
+					return fmt.Errorf("%s: %v", prefix, err)
+				}
+				ls.cache.archives[prefix] = a
+				return nil
+			}
+		}
+
+		parent := filepath.Dir(prefix)
+		if parent == prefix {
+			return nil
+		}
+		prefix = parent
+	}
+}
+
+// archiveFor returns the open archive name lies in and its path inside.
+func (ls *lister) archiveFor(name string) (*archive, string, bool) {
+	if ls.fsys != nil {
+		return nil, "", false
+	}
+	name = filepath.Clean(name)
+	ls.cache.archivesMu.RLock()
+	defer ls.cache.archivesMu.RUnlock()
+	for prefix, a := range ls.cache.archives {
+		if name == prefix {
+			return a, ".", true
+		}
+		if strings.HasPrefix(name, prefix+string(filepath.Separator)) {
+			return a, filepath.ToSlash(name[len(prefix)+1:]), true
+		}
+	}
+	return nil, "", false
+}
+
+// statPath is os.Stat, on Options.FS when set, that also looks inside
+// open archives. Like os.Stat, it describes what a symbolic link points
+// to under the name of the link.
+func (ls *lister) statPath(name string) (os.FileInfo, error) {
+	if a, inner, ok := ls.archiveFor(name); ok {
+		requested := path.Clean(inner)
+		inner = requested
+		entry, found := a.entries[inner]
+		for depth := 0; found && entry.linkTarget != "" && depth < 40; depth++ {
+			inner = path.Join(path.Dir(inner), entry.linkTarget)
+			entry, found = a.entries[inner]
+		}
+		if !found {
+			return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+		}
+		if inner != requested {
+			target := *entry
+			target.name = path.Base(requested)
+			return &target, nil
+		}
+		return entry, nil
+	}
+	return ls.sysStat(name)
+}
+
+// lstatPath is os.Lstat, on Options.FS when set, that also looks inside
+// open archives.
+func (ls *lister) lstatPath(name string) (os.FileInfo, error) {
+	if a, inner, ok := ls.archiveFor(name); ok {
+		entry, found := a.entries[path.Clean(inner)]
+		if !found {
+			return nil, &fs.PathError{Op: "lstat", Path: name, Err: fs.ErrNotExist}
+		}
+		return entry, nil
+	}
+	return ls.sysLstat(name)
+}
+
+// readDir is os.ReadDir, on Options.FS when set, that also lists
+// directories inside open archives.
+func (ls *lister) readDir(name string) ([]os.DirEntry, error) {
+	if a, inner, ok := ls.archiveFor(name); ok {
+		entry, found := a.entries[path.Clean(inner)]
+		if !found {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		}
+		if !entry.IsDir() {
+			return nil, &fs.PathError{Op: "readdirent", Path: name, Err: syscall.ENOTDIR}
+		}
+		return append([]os.DirEntry(nil), a.children[path.Clean(inner)]...), nil
+	}
+	return ls.sysReadDir(name)
+}
+
+// readLink is os.Readlink, on Options.FS when set, that also reads
+// symbolic links inside open archives.
+func (ls *lister) readLink(name string) (string, error) {
+	if a, inner, ok := ls.archiveFor(name); ok {
+		entry, found := a.entries[path.Clean(inner)]
+		if !found || entry.linkTarget == "" {
+			return "", &fs.PathError{Op: "readlink", Path: name, Err: syscall.EINVAL}
+		}
+		return entry.linkTarget, nil
+	}
+	return ls.sysReadLink(name)
+}
+
+// ownerName returns the name of the user owning a file, falling back to
+// the numeric id when the user is unknown.
+func ownerName(info os.FileInfo) string {
+	switch sys := info.Sys().(type) {
+	case *archiveEntry:
+		return sys.owner
+	case *syscall.Stat_t:
+		uid := strconv.FormatUint(uint64(sys.Uid), 10)
+		if u, err := user.LookupId(uid); err == nil {
+			return u.Username
+		}
+		return uid
+	}
+	return "-"
+}
+
+// groupName returns the name of the group owning a file, falling back to
+// the numeric id when the group is unknown.
+func groupName(info os.FileInfo) string {
+	switch sys := info.Sys().(type) {
+	case *archiveEntry:
+		return sys.group
+	case *syscall.Stat_t:
+		gid := strconv.FormatUint(uint64(sys.Gid), 10)
+		if g, err := user.LookupGroupId(gid); err == nil {
+			return g.Name
+		}
+		return gid
+	}
+	return "-"
+}
+
+func readArchive(name string, info os.FileInfo) (*archive, error) {
+	a := &archive{
+		entries:  map[string]*archiveEntry{},
+		children: map[string][]os.DirEntry{},
+	}
+	a.entries["."] = &archiveEntry{
+		name:    filepath.Base(name),
+		mode:    os.ModeDir | info.Mode().Perm(),
+		modTime: info.ModTime(),
+		owner:   ownerName(info),
+		group:   groupName(info),
+	}
+
+	format := archiveFormat(name)
+	var err error
+	if format == "zip" {
+		err = a.readZip(name)
+	} else {
+		err = a.readTar(name, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entries := range a.children {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	}
+	return a, nil
+}
+
+func (a *archive) readZip(name string) error {
+	r, err := zip.OpenReader(name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		entry := &archiveEntry{
+			size:    int64(f.UncompressedSize64),
+			mode:    f.Mode(),
+			modTime: f.Modified,
+			owner:   "-",
+			group:   "-",
+		}
+		// Zip stores the target of a symbolic link as its contents.
+		if entry.mode&os.ModeSymlink != 0 {
+			if rc, err := f.Open(); err == nil {
+				target, _ := io.ReadAll(io.LimitReader(rc, 4096))
+				rc.Close()
+				entry.linkTarget = string(target)
+			}
+		}
+		a.add(f.Name, entry)
+	}
+	return nil
+}
+
+func (a *archive) readTar(name, format string) (err error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if compression, found := tarCompressions[format]; found {
+		decompressorsMu.RLock()
+		decompress, found := decompressors[compression]
+		decompressorsMu.RUnlock()
+		if !found {
+			return fmt.Errorf("unsupported compression %s", compression)
+		}
+		rc, decompressErr := decompress(f)
+		if decompressErr != nil {
+			return decompressErr
+		}
+		// A decompressor may only find the stream corrupt once it is
+		// done, which it reports when closed.
+		defer func() {
+			if closeErr := rc.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+		r = rc
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+
+		entry := &archiveEntry{
+			size:    header.Size,
+			mode:    header.FileInfo().Mode(),
+			modTime: header.ModTime,
+			owner:   header.Uname,
+			group:   header.Gname,
+		}
+		if entry.owner == "" {
+			entry.owner = strconv.Itoa(header.Uid)
+		}
+		if entry.group == "" {
+			entry.group = strconv.Itoa(header.Gid)
+		}
+		if header.Typeflag == tar.TypeSymlink {
+			entry.linkTarget = header.Linkname
+		}
+		a.add(header.Name, entry)
+	}
+	return nil
+}
+
+// add records an entry under its cleaned path, creating the parent
+// directories that archives often leave implicit.
+func (a *archive) add(name string, entry *archiveEntry) {
+	name = path.Clean("/" + name)[1:]
+	if name == "" {
+		return
+	}
+	entry.name = path.Base(name)
+
+	if existing, found := a.entries[name]; found {
+		// A directory first seen as the parent of an entry gets its real
+		// header when that comes later.
+		*existing = *entry
+		return
+	}
+	a.entries[name] = entry
+
+	parent := path.Dir(name)
+	if _, found := a.entries[parent]; !found {
+		a.add(parent, &archiveEntry{
+			mode:    os.ModeDir | 0755,
+			modTime: entry.modTime,
+			owner:   entry.owner,
+			group:   entry.group,
+		})
+	}
+	a.children[parent] = append(a.children[parent], entry)
+}
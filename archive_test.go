@@ -0,0 +1,214 @@
+package gols
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// archiveFile is one entry written by the archive writers below; a
+// target makes it a symbolic link and a trailing slash a directory.
+type archiveFile struct {
+	name   string
+	data   string
+	target string
+}
+
+var archiveFiles = []archiveFile{
+	{name: "src/"},
+	{name: "src/main.go", data: "package main\n"},
+	{name: "src/tool", data: "#!/bin/sh\n"},
+	{name: "src/lnk", target: "tool"},
+	{name: "docs/guide.md", data: "# guide\n"},
+}
+
+func writeTar(t *testing.T, name string, compress bool) {
+	t.Helper()
+	var buf bytes.Buffer
+	var gz *gzip.Writer
+	tw := tar.NewWriter(&buf)
+	if compress {
+		gz = gzip.NewWriter(&buf)
+		tw = tar.NewWriter(gz)
+	}
+	for _, file := range archiveFiles {
+		header := &tar.Header{Name: file.name, Mode: 0o644, ModTime: time.Unix(1700000000, 0), Uname: "gols", Gname: "staff"}
+		switch {
+		case strings.HasSuffix(file.name, "/"):
+			header.Typeflag, header.Mode = tar.TypeDir, 0o755
+		case file.target != "":
+			header.Typeflag, header.Linkname = tar.TypeSymlink, file.target
+		default:
+			header.Typeflag, header.Size = tar.TypeReg, int64(len(file.data))
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(file.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(name, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeZip(t *testing.T, name string) {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, file := range archiveFiles {
+		header := &zip.FileHeader{Name: file.name, Modified: time.Unix(1700000000, 0)}
+		switch {
+		case strings.HasSuffix(file.name, "/"):
+			header.SetMode(os.ModeDir | 0o755)
+		case file.target != "":
+			header.SetMode(os.ModeSymlink | 0o777)
+		default:
+			header.SetMode(0o644)
+		}
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(file.data + file.target)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestArchives(t *testing.T) {
+	dir := t.TempDir()
+	writeTar(t, filepath.Join(dir, "r.tar"), false)
+	writeTar(t, filepath.Join(dir, "r.tar.gz"), true)
+	writeTar(t, filepath.Join(dir, "r.tgz"), true)
+	writeZip(t, filepath.Join(dir, "r.zip"))
+
+	for _, name := range []string{"r.tar", "r.tar.gz", "r.tgz", "r.zip"} {
+		ls := defaultLister()
+		archive := filepath.Join(dir, name)
+		if err := ls.openArchive(filepath.Join(archive, "src")); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		files, err := ls.readDir(archive)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var names []string
+		for _, file := range files {
+			names = append(names, file.Name())
+		}
+		if got := strings.Join(names, " "); got != "docs src" {
+			t.Errorf("%s: top level = %s, want docs src", name, got)
+		}
+
+		files, err = ls.readDir(filepath.Join(archive, "src"))
+		if err != nil || len(files) != 3 {
+			t.Fatalf("%s: src = %v, %v", name, files, err)
+		}
+		sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+		if files[0].Name() != "lnk" || files[0].Type()&os.ModeSymlink == 0 {
+			t.Errorf("%s: %s is not a symlink: %v", name, files[0].Name(), files[0].Type())
+		}
+
+		target, err := ls.readLink(filepath.Join(archive, "src", "lnk"))
+		if err != nil || target != "tool" {
+			t.Errorf("%s: readLink = %q, %v, want tool", name, target, err)
+		}
+
+		info, err := ls.statPath(filepath.Join(archive, "src", "lnk"))
+		if err != nil || info.Name() != "lnk" || !info.Mode().IsRegular() || info.Size() != int64(len("#!/bin/sh\n")) {
+			t.Errorf("%s: statPath of the link = %v, %v, want the tool under the name lnk", name, info, err)
+		}
+		info, err = ls.lstatPath(filepath.Join(archive, "src", "lnk"))
+		if err != nil || info.Name() != "lnk" || info.Mode()&os.ModeSymlink == 0 {
+			t.Errorf("%s: lstatPath of the link = %v, %v", name, info, err)
+		}
+
+		if _, err := ls.statPath(filepath.Join(archive, "missing")); !os.IsNotExist(err) {
+			t.Errorf("%s: statPath of a missing entry: %v", name, err)
+		}
+		if _, err := ls.readDir(filepath.Join(archive, "src", "main.go")); err == nil {
+			t.Errorf("%s: readDir of a file succeeded", name)
+		}
+	}
+}
+
+func TestArchiveUnsupportedCompression(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"r.tar.xz", "r.tar.zst"} {
+		archive := filepath.Join(dir, name)
+		if err := os.WriteFile(archive, []byte("not read"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		err := defaultLister().openArchive(filepath.Join(archive, "bin"))
+		if err == nil || !strings.Contains(err.Error(), "unsupported compression") {
+			t.Errorf("%s: openArchive = %v, want an unsupported compression error", name, err)
+		}
+	}
+}
+
+// TestUnreadableArchive lists a file that only has an archive's name as
+// a plain file, while paths inside it still fail.
+func TestUnreadableArchive(t *testing.T) {
+	dir := t.TempDir()
+	notes := filepath.Join(dir, "notes.zip")
+	if err := os.WriteFile(notes, []byte("not a zip file\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := Print(&buf, notes, Options{OneColumn: true, NoIcons: true}); err != nil {
+		t.Fatalf("Print(notes.zip) = %v", err)
+	}
+	if got := ansiEscapes.ReplaceAllString(buf.String(), ""); strings.TrimSpace(got) != "notes.zip" {
+		t.Errorf("Print(notes.zip) = %q, want notes.zip", got)
+	}
+
+	if _, err := Print(&buf, filepath.Join(notes, "bin"), Options{}); err == nil {
+		t.Errorf("Print(notes.zip/bin) succeeded")
+	}
+}
+
+func TestArchiveFormat(t *testing.T) {
+	tests := map[string]string{
+		"a.zip":     "zip",
+		"a.JAR":     "zip",
+		"a.tar":     "tar",
+		"a.tar.gz":  "tar.gz",
+		"a.tgz":     "tar.gz",
+		"a.tbz2":    "tar.bz2",
+		"a.txz":     "tar.xz",
+		"a.tar.zst": "tar.zst",
+		"a.gz":      "",
+		"a.tar.bak": "",
+		"zip":       "",
+	}
+	for name, want := range tests {
+		if got := archiveFormat(name); got != want {
+			t.Errorf("archiveFormat(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
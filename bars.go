@@ -0,0 +1,81 @@
+package gols
+
+import (
+	"fmt"
+	"strings"
+)
+
+// barWidth is the length of the longest bar, in cells.
+const barWidth = 20
+
+// barEighths are the partial blocks used for the fractional end of a
+// bar, from one eighth of a cell to seven.
+var barEighths = []string{"", "▏", "▎", "▍", "▌", "▋", "▊", "▉"}
+
+// barScale holds what the bars of one listing are relative to: the
+// largest size sets the full length and the total sets the colours.
+// Directories only get a bar when their sizes are computed (--du).
+type barScale struct {
+	largest int64
+	total   int64
+	dirs    bool
+}
+
+// newBarScale measures the entries a listing shows. Without --du,
+// directories are left out, as their own size says nothing about their
+// contents. With it, the total is that of the outermost entries, which
+// already include everything a tree shows below them.
+func (ls *lister) newBarScale(entries []Entry) barScale {
+	scale := barScale{dirs: ls.opts.DirSizes}
+	top := -1
+	for _, entry := range entries {
+		if top < 0 || entry.Depth < top {
+			top = entry.Depth
+		}
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && !scale.dirs {
+			continue
+		}
+		size := ls.entrySize(entry)
+		if size > scale.largest {
+			scale.largest = size
+		}
+		if !scale.dirs || entry.Depth == top {
+			scale.total += size
+		}
+	}
+	return scale
+}
+
+// bar draws size as a bar of barWidth cells followed by its share of the
+// total. Entries with a large share are red, medium ones yellow and small
+// ones green. Directories without a computed size get blank space of the
+// same width.
+func (s barScale) bar(size int64, isDir bool) string {
+	blank := strings.Repeat(" ", barWidth+7)
+	if (isDir && !s.dirs) || s.largest == 0 {
+		return blank
+	}
+
+	cells := float64(size) / float64(s.largest) * barWidth
+	full := int(cells)
+	bar := strings.Repeat("█", full) + barEighths[int((cells-float64(full))*8)]
+	if size > 0 && bar == "" {
+		bar = barEighths[1]
+	}
+	padding := barWidth - full
+	if bar != strings.Repeat("█", full) {
+		padding--
+	}
+
+	share := float64(size) / float64(s.total)
+	color := green
+	switch {
+	case share >= 0.25:
+		color = red
+	case share >= 0.10:
+		color = yellow
+	}
+	return color + bar + reset + strings.Repeat(" ", padding) + fmt.Sprintf(" %5.1f%%", share*100)
+}
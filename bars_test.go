@@ -0,0 +1,84 @@
+package gols
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestBar(t *testing.T) {
+	scale := barScale{largest: 800, total: 1600}
+	tests := []struct {
+		name  string
+		scale barScale
+		size  int64
+		isDir bool
+		bar   string
+		color string
+		share string
+	}{
+		{name: "largest", scale: scale, size: 800, bar: strings.Repeat("█", 20), color: red, share: "  50.0%"},
+		{name: "half", scale: scale, size: 400, bar: strings.Repeat("█", 10), color: red, share: "  25.0%"},
+		{name: "partial cell", scale: scale, size: 250, bar: "██████▎", color: yellow, share: "  15.6%"},
+		{name: "tiny", scale: scale, size: 1, bar: "▏", color: green, share: "   0.1%"},
+		{name: "zero", scale: scale, size: 0, bar: "", color: green, share: "   0.0%"},
+		{name: "directory", scale: scale, size: 800, isDir: true},
+		{name: "directory with --du", scale: barScale{largest: 800, total: 1600, dirs: true}, size: 800, isDir: true, bar: strings.Repeat("█", 20), color: red, share: "  50.0%"},
+		{name: "nothing to scale", scale: barScale{}, size: 0},
+	}
+	for _, test := range tests {
+		got := test.scale.bar(test.size, test.isDir)
+		if visibleWidth(got) != barWidth+7 {
+			t.Errorf("%s: bar %q is %d cells wide", test.name, got, visibleWidth(got))
+		}
+		want := strings.Repeat(" ", barWidth+7)
+		if test.color != "" {
+			want = test.color + test.bar + reset + strings.Repeat(" ", barWidth-visibleWidth(test.bar)) + test.share
+		}
+		if got != want {
+			t.Errorf("%s: bar = %q, want %q", test.name, got, want)
+		}
+	}
+}
+
+func TestNewBarScale(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a":          {Data: make([]byte, 100)},
+		"b":          {Data: make([]byte, 300)},
+		"dir/c":      {Data: make([]byte, 500)},
+		"dir/sub/d":  {Data: make([]byte, 50)},
+		"dir/.e":     {Data: make([]byte, 25)},
+		"other/f.go": {Data: make([]byte, 10)},
+	}
+	tests := []struct {
+		name    string
+		opts    func(*Options)
+		largest int64
+		total   int64
+	}{
+		{name: "files", largest: 300, total: 400},
+		{name: "directory sizes", opts: func(o *Options) { o.DirSizes = true }, largest: 575, total: 985},
+		{name: "tree", opts: func(o *Options) { o.Recursive = true }, largest: 500, total: 960},
+		{name: "tree with directory sizes", opts: func(o *Options) { o.Recursive, o.DirSizes = true, true }, largest: 575, total: 985},
+	}
+	for _, test := range tests {
+		opts := DefaultOptions()
+		opts.FS = fsys
+		if test.opts != nil {
+			test.opts(&opts)
+		}
+		ls, err := newLister(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l, err := ls.newListing(context.Background(), ".")
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		scale := ls.newBarScale(l.Entries)
+		if scale.largest != test.largest || scale.total != test.total || scale.dirs != opts.DirSizes {
+			t.Errorf("%s: scale = %+v, want largest %d, total %d", test.name, scale, test.largest, test.total)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package gols
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+var checksumAlgorithms = map[string]func() hash.Hash{
+	"sha256":  sha256.New,
+	"sha512":  sha512.New,
+	"sha1":    sha1.New,
+	"md5":     md5.New,
+	"blake2b": func() hash.Hash { h, _ := blake2b.New512(nil); return h },
+	"crc32":   func() hash.Hash { return crc32.NewIEEE() },
+}
+
+// loadChecksums hashes the regular files among entries with a pool of one
+// worker per CPU, so that printing can then look them up in order.
+func (ls *lister) loadChecksums(entries []Entry) {
+	var paths []string
+	for _, entry := range entries {
+		if entry.Info.Mode().IsRegular() {
+			paths = append(paths, entry.Path)
+		}
+	}
+	for path, sum := range ls.hashFiles(paths, checksumAlgorithms[ls.opts.Checksum]) {
+		ls.cache.checksums[path] = sum
+	}
+}
+
+// hashFiles returns the hex digest of every readable file in paths.
+func (ls *lister) hashFiles(paths []string, newHash func() hash.Hash) map[string]string {
+	sums := map[string]string{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	jobs := make(chan string)
+	for i := 0; i < min(runtime.NumCPU(), len(paths)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				sum, err := ls.checksumFile(path, newHash())
+				if err != nil {
+					continue
+				}
+				mu.Lock()
+				sums[path] = sum
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, path := range paths {
+		jobs <- path
+	}
+	close(jobs)
+	wg.Wait()
+	return sums
+}
+
+func (ls *lister) checksumFile(path string, h hash.Hash) (string, error) {
+	f, err := ls.sysOpen(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// checksumColumn returns the digest of name in directory followed by a
+// space, blank padding for directories and other entries that have no
+// digest, or "" when --checksum is off.
+func (ls *lister) checksumColumn(directory, name string) string {
+	if ls.opts.Checksum == "" {
+		return ""
+	}
+	width := checksumAlgorithms[ls.opts.Checksum]().Size() * 2
+
+	sum, found := ls.cache.checksums[filepath.Join(directory, name)]
+	if !found {
+		return gray + padRight("-", width) + reset + " "
+	}
+	return yellow + sum + reset + " "
+}
+
+// checksumsForLength returns the algorithms whose hex digests have the
+// given length, as the *sum tools all share one format and only the
+// length tells them apart. sha512sum and b2sum cannot be told apart at
+// all.
+func checksumsForLength(length int) []string {
+	switch length {
+	case 8:
+		return []string{"crc32"}
+	case 32:
+		return []string{"md5"}
+	case 40:
+		return []string{"sha1"}
+	case 64:
+		return []string{"sha256"}
+	case 128:
+		return []string{"sha512", "blake2b"}
+	}
+	return nil
+}
+
+// sumsAlgorithms returns the algorithms that could have written sums.
+func sumsAlgorithms(sums map[string]string) ([]string, error) {
+	length := -1
+	for _, sum := range sums {
+		if length >= 0 && len(sum) != length {
+			return nil, errors.New("digests of different lengths")
+		}
+		length = len(sum)
+	}
+	if length < 0 {
+		return []string{"sha256"}, nil
+	}
+	algorithms := checksumsForLength(length)
+	if len(algorithms) == 0 {
+		return nil, fmt.Errorf("no known algorithm has %d-digit digests", length)
+	}
+	return algorithms, nil
+}
+
+// readSumsFile parses a file in the format written by sha256sum and its
+// siblings: a hex digest, a space, a space or '*', and a path.
+func readSumsFile(name string) (map[string]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sums := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		sum, path, found := strings.Cut(text, " ")
+		if !found || len(path) < 2 || (path[0] != ' ' && path[0] != '*') {
+			return nil, fmt.Errorf("%s:%d: not a checksum line", name, line)
+		}
+		if _, err := hex.DecodeString(sum); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid digest", name, line)
+		}
+		sums[filepath.Clean(path[1:])] = strings.ToLower(sum)
+	}
+	return sums, scanner.Err()
+}
+
+// Verify checks the files of directory against sumsFile and writes the
+// result to w, like gols --verify. It reports whether everything
+// matched.
+func Verify(w io.Writer, sumsFile, directory string, opts Options) (bool, error) {
+	ls, err := newLister(opts)
+	if err != nil {
+		return false, err
+	}
+	return ls.runVerify(w, sumsFile, directory)
+}
+
+// runVerify compares the files of directory against sumsFile, the sums
+// file given to --verify. Paths in the sums file are relative to directory. Listed
+// files are marked OK or CHANGED, files of the sums file that do not
+// exist are marked MISSING, and listed files it does not mention are
+// shown unmarked. It reports whether everything matched.
+func (ls *lister) runVerify(w io.Writer, sumsFile, directory string) (bool, error) {
+	sums, err := readSumsFile(sumsFile)
+	if err != nil {
+		return false, err
+	}
+
+	algorithms := []string{ls.opts.Checksum}
+	if ls.opts.Checksum == "" {
+		if algorithms, err = sumsAlgorithms(sums); err != nil {
+			return false, fmt.Errorf("%s: %v", sumsFile, err)
+		}
+	}
+
+	files, err := ls.readDir(directory)
+	if err != nil {
+		return false, err
+	}
+	if !ls.showHidden {
+		files = filterHidden(files)
+	}
+	if ls.matcher.active() {
+		files = ls.filterNames(files, "")
+	}
+
+	entries := map[string]os.DirEntry{}
+	for _, file := range files {
+		if file.Type().IsRegular() {
+			entries[file.Name()] = file
+		}
+	}
+	for name := range sums {
+		if _, found := entries[name]; !found {
+			if entry, err := ls.lstatPath(filepath.Join(directory, name)); err == nil && entry.Mode().IsRegular() {
+				entries[name] = &fakeDirEntry{entry}
+			}
+		}
+	}
+	var paths []string
+	for name := range entries {
+		paths = append(paths, filepath.Join(directory, name))
+	}
+
+	// When the digest length fits several algorithms, the one that
+	// matches the most files is taken to have written the sums file.
+	var actual map[string]string
+	best := -1
+	for _, algorithm := range algorithms {
+		digests := ls.hashFiles(paths, checksumAlgorithms[algorithm])
+		matches := 0
+		for name, sum := range sums {
+			if digests[filepath.Join(directory, name)] == sum {
+				matches++
+			}
+		}
+		if matches > best {
+			actual, best = digests, matches
+		}
+		if matches == len(sums) {
+			break
+		}
+	}
+
+	names := make([]string, 0, len(entries)+len(sums))
+	for name := range entries {
+		names = append(names, name)
+	}
+	for name := range sums {
+		if _, found := entries[name]; !found {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	ok := true
+	counts := map[string]int{}
+	for _, name := range names {
+		expected, listed := sums[name]
+		entry, exists := entries[name]
+
+		var status string
+		switch {
+		case !exists:
+			status = red + "MISSING" + reset
+			counts["missing"]++
+			ok = false
+		case !listed:
+			status = gray + "-" + reset
+		case actual[filepath.Join(directory, name)] == expected:
+			status = green + "OK" + reset
+			counts["ok"]++
+		default:
+			status = red + "CHANGED" + reset
+			counts["changed"]++
+			ok = false
+		}
+		status += strings.Repeat(" ", len("MISSING")-visibleWidth(status))
+
+		if !exists {
+			fmt.Fprintf(w, "%s %s%s%s\n", status, gray, name, reset)
+			continue
+		}
+		info, _ := entry.Info()
+		fmt.Fprintf(w, "%s %s%s\n", status, ls.getFileIcon(entry, info.Mode(), filepath.Join(directory, filepath.Dir(name))), name)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%s%d%s OK, %s%d%s changed, %s%d%s missing\n",
+		green, counts["ok"], reset, red, counts["changed"], reset, red, counts["missing"], reset)
+	return ok, nil
+}
@@ -0,0 +1,170 @@
+package gols
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestChecksumAlgorithms(t *testing.T) {
+	tests := map[string]string{
+		"sha256":  "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+		"sha512":  "9b71d224bd62f3785d96d46ad3ea3d73319bfbc2890caadae2dff72519673ca72323c3d99ba5c11d7c7acc6e14b8c5da0c4663475c2e5c3adef46f73bcdec043",
+		"sha1":    "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d",
+		"md5":     "5d41402abc4b2a76b9719d911017c592",
+		"blake2b": "e4cfa39a3d37be31c59609e807970799caa68a19bfaa15135f165085e01d41a65ba1e1b146aeb6bd0092b49eac214c103ccfa3a365954bbbe52f74a2b3620c94",
+		"crc32":   "3610a686",
+	}
+	if len(tests) != len(checksumAlgorithms) {
+		t.Errorf("%d algorithms tested, %d known", len(tests), len(checksumAlgorithms))
+	}
+	for algorithm, want := range tests {
+		newHash, found := checksumAlgorithms[algorithm]
+		if !found {
+			t.Errorf("%s: unknown algorithm", algorithm)
+			continue
+		}
+		h := newHash()
+		h.Write([]byte("hello"))
+		if got := hex.EncodeToString(h.Sum(nil)); got != want {
+			t.Errorf("%s(hello) = %s, want %s", algorithm, got, want)
+		}
+		if got := checksumsForLength(len(want)); !slices.Contains(got, algorithm) {
+			t.Errorf("checksumsForLength(%d) = %v, want it to include %s", len(want), got, algorithm)
+		}
+	}
+}
+
+func TestReadSumsFile(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want map[string]string
+		err  string
+	}{
+		{
+			name: "text and binary",
+			data: "# sums\n5D41402ABC4B2A76B9719D911017C592  hello.txt\r\n\n5d41402abc4b2a76b9719d911017c592 *./bin/tool\n",
+			want: map[string]string{"hello.txt": "5d41402abc4b2a76b9719d911017c592", "bin/tool": "5d41402abc4b2a76b9719d911017c592"},
+		},
+		{name: "name with spaces", data: "3610a686  a b.txt\n", want: map[string]string{"a b.txt": "3610a686"}},
+		{name: "empty", data: "", want: map[string]string{}},
+		{name: "no separator", data: "3610a686\n", err: ":1: not a checksum line"},
+		{name: "one space", data: "ok\n3610a686 x\n", err: ":1: not a checksum line"},
+		{name: "bad digest", data: "3610a68z  x\n", err: ":1: invalid digest"},
+	}
+	for _, test := range tests {
+		name := filepath.Join(t.TempDir(), "SUMS")
+		if err := os.WriteFile(name, []byte(test.data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := readSumsFile(name)
+		if test.err != "" {
+			if err == nil || !strings.HasSuffix(err.Error(), test.err) {
+				t.Errorf("%s: readSumsFile error = %v, want %q", test.name, err, test.err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: readSumsFile = %v, %v, want %v", test.name, got, err, test.want)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	const (
+		hello        = "5d41402abc4b2a76b9719d911017c592"
+		helloSHA512  = "9b71d224bd62f3785d96d46ad3ea3d73319bfbc2890caadae2dff72519673ca72323c3d99ba5c11d7c7acc6e14b8c5da0c4663475c2e5c3adef46f73bcdec043"
+		helloBLAKE2b = "e4cfa39a3d37be31c59609e807970799caa68a19bfaa15135f165085e01d41a65ba1e1b146aeb6bd0092b49eac214c103ccfa3a365954bbbe52f74a2b3620c94"
+	)
+	tests := []struct {
+		name  string
+		sums  string
+		files map[string]string
+		want  []string
+		ok    bool
+		err   string
+	}{
+		{
+			name:  "all match",
+			sums:  hello + "  a.txt\n" + hello + "  b.txt\n",
+			files: map[string]string{"a.txt": "hello", "b.txt": "hello"},
+			want:  []string{"OK      a.txt", "OK      b.txt", "2 OK, 0 changed, 0 missing"},
+			ok:    true,
+		},
+		{
+			name:  "changed, missing and unlisted",
+			sums:  hello + "  a.txt\n" + hello + "  gone.txt\n",
+			files: map[string]string{"a.txt": "HELLO", "new.txt": "new"},
+			want:  []string{"CHANGED a.txt", "MISSING gone.txt", "-       new.txt", "0 OK, 1 changed, 1 missing"},
+		},
+		{
+			name:  "in a subdirectory",
+			sums:  hello + "  sub/a.txt\n",
+			files: map[string]string{"sub/a.txt": "hello"},
+			want:  []string{"OK      sub/a.txt", "1 OK, 0 changed, 0 missing"},
+			ok:    true,
+		},
+		{
+			name:  "sha512sum",
+			sums:  helloSHA512 + "  a.txt\n" + helloSHA512 + "  b.txt\n",
+			files: map[string]string{"a.txt": "hello", "b.txt": "HELLO"},
+			want:  []string{"OK      a.txt", "CHANGED b.txt", "1 OK, 1 changed, 0 missing"},
+		},
+		{
+			name:  "b2sum",
+			sums:  helloBLAKE2b + "  a.txt\n",
+			files: map[string]string{"a.txt": "hello"},
+			want:  []string{"OK      a.txt", "1 OK, 0 changed, 0 missing"},
+			ok:    true,
+		},
+		{
+			name:  "mixed lengths",
+			sums:  hello + "  a.txt\n" + helloSHA512 + "  b.txt\n",
+			files: map[string]string{"a.txt": "hello"},
+			err:   "digests of different lengths",
+		},
+		{
+			name:  "unknown length",
+			sums:  "abcd  a.txt\n",
+			files: map[string]string{"a.txt": "hello"},
+			err:   "no known algorithm has 4-digit digests",
+		},
+	}
+	for _, test := range tests {
+		dir := t.TempDir()
+		writeFiles(t, dir, test.files)
+		sums := filepath.Join(t.TempDir(), "MD5SUMS")
+		if err := os.WriteFile(sums, []byte(test.sums), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		opts := DefaultOptions()
+		opts.NoIcons = true
+		var out bytes.Buffer
+		ok, err := Verify(&out, sums, dir, opts)
+		if test.err != "" {
+			if err == nil || !strings.HasSuffix(err.Error(), test.err) {
+				t.Errorf("%s: Verify error = %v, want %q", test.name, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		var got []string
+		for _, line := range strings.Split(ansiEscapes.ReplaceAllString(out.String(), ""), "\n") {
+			if line != "" {
+				got = append(got, line)
+			}
+		}
+		if ok != test.ok || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: Verify = %v\n%s\nwant %v\n%s", test.name, ok, strings.Join(got, "\n"), test.ok, strings.Join(test.want, "\n"))
+		}
+	}
+}
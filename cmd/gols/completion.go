@@ -0,0 +1,358 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/elbachir-one/gols"
+)
+
+// completionDepths are offered for the value of -d.
+var completionDepths = []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9"}
+
+var completionScripts = map[string]func(w io.Writer, flags []flagDef){
+	"bash": writeBashCompletion,
+	"zsh":  writeZshCompletion,
+	"fish": writeFishCompletion,
+}
+
+func completionShells() []string {
+	shells := make([]string, 0, len(completionScripts))
+	for shell := range completionScripts {
+		shells = append(shells, shell)
+	}
+	sort.Strings(shells)
+	return shells
+}
+
+// printCompletion writes the completion script for shell, one of
+// completionShells, generated from the flags of --help.
+func printCompletion(w io.Writer, shell string) {
+	completionScripts[shell](w, allFlags())
+}
+
+// printExtensions lists the extensions of the files in directory, one
+// per line, for the scripts to complete -e and -x with. Hidden files
+// count, so that -a -e finds theirs too.
+func printExtensions(w io.Writer, directory string) {
+	o := gols.DefaultOptions()
+	o.All = true
+	entries, err := gols.List(context.Background(), directory, o)
+	if err != nil {
+		return
+	}
+
+	seen := map[string]bool{}
+	var exts []string
+	for _, entry := range entries {
+		ext := strings.TrimPrefix(filepath.Ext(entry.Name), ".")
+		if entry.IsDir() || ext == "" || seen[ext] {
+			continue
+		}
+		seen[ext] = true
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	for _, ext := range exts {
+		fmt.Fprintln(w, ext)
+	}
+}
+
+// flagNames returns how flag is written on the command line.
+func (f flagDef) flagNames() []string {
+	var names []string
+	if f.short != "" {
+		names = append(names, "-"+f.short)
+	}
+	if f.long != "" {
+		names = append(names, "--"+f.long)
+	}
+	return names
+}
+
+// valueFlags returns the flags that take a value after a space: the
+// short ones as letters, the long ones with their dashes.
+func valueFlags(flags []flagDef) (shorts string, longs []string) {
+	for _, f := range flags {
+		if f.value != requiredValue {
+			continue
+		}
+		if f.short != "" {
+			shorts += f.short
+		}
+		if f.long != "" {
+			longs = append(longs, "--"+f.long)
+		}
+	}
+	return shorts, longs
+}
+
+// shQuote quotes s for bash and zsh.
+func shQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// fishQuote quotes s for fish, whose single quotes take \' and \\.
+func fishQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
+
+func writeBashCompletion(w io.Writer, flags []flagDef) {
+	shorts, longs := valueFlags(flags)
+
+	io.WriteString(w, `# bash completion for gols, generated by gols --completion bash.
+# Load it with: source <(gols --completion bash)
+
+_gols_flags=(
+`)
+	for _, f := range flags {
+		for _, name := range f.flagNames() {
+			fmt.Fprintf(w, "    %s\n", shQuote(name+"\t"+f.desc))
+		}
+	}
+	io.WriteString(w, `)
+
+# _gols_dir prints the directory being listed: the first argument that
+# is neither a flag nor the value of one.
+_gols_dir() {
+    local i w skip=
+    for ((i = 1; i < COMP_CWORD; i++)); do
+        w=${COMP_WORDS[i]}
+        if [[ -n $skip ]]; then
+            skip=
+            continue
+        fi
+        case $w in
+            =) skip=1 ;;
+`)
+	fmt.Fprintf(w, "            %s) [[ ${COMP_WORDS[i+1]} != = ]] && skip=1 ;;\n", strings.Join(longs, "|"))
+	fmt.Fprintf(w, `            --*) ;;
+            -*[%s]) skip=1 ;;
+            -*) ;;
+            *)
+                printf '%%s\n' "${w/#\~/$HOME}"
+                return
+                ;;
+        esac
+    done
+    echo .
+}
+
+_gols() {
+    local cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]} opt=
+    COMPREPLY=()
+
+    # bash splits --flag=value at the =.
+    if [[ $cur == = ]]; then
+        opt=$prev
+        cur=
+    elif [[ $prev == = && $COMP_CWORD -ge 2 ]]; then
+        opt=${COMP_WORDS[COMP_CWORD-2]}
+    else
+        case $prev in
+            %s) opt=$prev ;;
+            --*) ;;
+            -*[%s]) opt=-${prev: -1} ;;
+        esac
+    fi
+
+    case $opt in
+`, shorts, strings.Join(longs, "|"), shorts)
+	for _, f := range flags {
+		if f.value == noValue {
+			continue
+		}
+		var reply string
+		switch {
+		case f.values != nil:
+			reply = fmt.Sprintf(`COMPREPLY=($(compgen -W %s -- "$cur"))`, shQuote(strings.Join(f.values(), " ")))
+		case f.complete == completeExtensions:
+			reply = `local pre=
+            [[ $cur == *,* ]] && pre=${cur%,*},
+            COMPREPLY=($(compgen -P "$pre" -W "$("${COMP_WORDS[0]}" --complete-ext "$(_gols_dir)" 2>/dev/null)" -- "${cur##*,}"))`
+		case f.complete == completeDepth:
+			reply = fmt.Sprintf(`COMPREPLY=($(compgen -W %s -- "$cur"))`, shQuote(strings.Join(completionDepths, " ")))
+		case f.complete == completeFile:
+			reply = `COMPREPLY=($(compgen -f -- "$cur"))`
+		case f.complete == completeDir:
+			reply = `COMPREPLY=($(compgen -d -- "$cur"))`
+		case f.complete == completeUser:
+			reply = `COMPREPLY=($(compgen -u -- "$cur"))`
+		case f.complete == completeGroup:
+			reply = `COMPREPLY=($(compgen -g -- "$cur"))`
+		}
+		fmt.Fprintf(w, "        %s)\n", strings.Join(f.flagNames(), "|"))
+		if reply != "" {
+			fmt.Fprintf(w, "            %s\n", reply)
+		}
+		io.WriteString(w, "            return\n            ;;\n")
+	}
+	io.WriteString(w, `    esac
+
+    if [[ $cur == -* ]]; then
+        # Flags show their descriptions while there is a choice.
+        local line flag matches=() descs=() i
+        for line in "${_gols_flags[@]}"; do
+            flag=${line%%$'\t'*}
+            if [[ $flag == "$cur"* ]]; then
+                matches+=("$flag")
+                descs+=("${line#*$'\t'}")
+            fi
+        done
+        if ((${#matches[@]} == 1)); then
+            COMPREPLY=("${matches[0]}")
+        else
+            for i in "${!matches[@]}"; do
+                COMPREPLY+=("$(printf '%-16s (%s)' "${matches[i]}" "${descs[i]}")")
+            done
+        fi
+        return
+    fi
+
+    COMPREPLY=($(compgen -d -- "$cur"))
+}
+
+complete -o filenames -F _gols gols
+`)
+}
+
+// zshEscape escapes the characters _arguments gives a meaning to in an
+// option description.
+func zshEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`, ":", `\:`).Replace(s)
+}
+
+func writeZshCompletion(w io.Writer, flags []flagDef) {
+	io.WriteString(w, `#compdef gols
+# zsh completion for gols, generated by gols --completion zsh.
+# Load it with: source <(gols --completion zsh)
+
+_gols_extensions() {
+    local dir=${line[1]:-.}
+    local -a exts
+    exts=(${(f)"$(${words[1]} --complete-ext ${dir/#\~/$HOME} 2>/dev/null)"})
+    (( $#exts )) && _values -s , extension $exts
+}
+
+_gols() {
+    _arguments -s \
+`)
+	for _, f := range flags {
+		var action string
+		switch {
+		case f.values != nil:
+			action = "(" + strings.Join(f.values(), " ") + ")"
+		case f.complete == completeExtensions:
+			action = "_gols_extensions"
+		case f.complete == completeDepth:
+			action = "(" + strings.Join(completionDepths, " ") + ")"
+		case f.complete == completeFile:
+			action = "_files"
+		case f.complete == completeDir:
+			action = "_files -/"
+		case f.complete == completeUser:
+			action = "_users"
+		case f.complete == completeGroup:
+			action = "_groups"
+		default:
+			action = " "
+		}
+		message := f.long
+		if message == "" {
+			message = f.complete
+		}
+
+		for _, name := range f.flagNames() {
+			spec := name
+			switch {
+			case f.value == requiredValue && f.long != "" && name == "--"+f.long:
+				spec += "=[" + zshEscape(f.desc) + "]:" + message + ":" + action
+			case f.value == requiredValue:
+				spec += "+[" + zshEscape(f.desc) + "]:" + message + ":" + action
+			case f.value == optionalValue:
+				spec += "=-[" + zshEscape(f.desc) + "]::" + message + ":" + action
+			default:
+				spec += "[" + zshEscape(f.desc) + "]"
+			}
+			fmt.Fprintf(w, "        %s \\\n", shQuote(spec))
+		}
+	}
+	io.WriteString(w, `        '*:directory:_files -/'
+}
+
+compdef _gols gols
+`)
+}
+
+func writeFishCompletion(w io.Writer, flags []flagDef) {
+	shorts, longs := valueFlags(flags)
+
+	fmt.Fprintf(w, `# fish completion for gols, generated by gols --completion fish.
+# Load it with: gols --completion fish | source
+
+# __gols_dir prints the directory being listed: the first argument that
+# is neither a flag nor the value of one.
+function __gols_dir
+    set -l tokens (commandline -opc)
+    set -l skip 0
+    for token in $tokens[2..-1]
+        if test $skip -eq 1
+            set skip 0
+        else if contains -- $token %s
+            set skip 1
+        else if string match -qr -- '^-[^-]*[%s]$' $token
+            set skip 1
+        else if not string match -q -- '-*' $token
+            string replace -r '^~' $HOME -- $token
+            return
+        end
+    end
+    echo .
+end
+
+function __gols_extensions
+    set -l gols (commandline -opc)[1]
+    set -l prefix (string match -r -- '.*,' (commandline -ct))
+    for ext in ($gols --complete-ext (__gols_dir) 2>/dev/null)
+        echo $prefix$ext
+    end
+end
+
+complete -c gols -f -a '(__fish_complete_directories)'
+`, strings.Join(longs, " "), shorts)
+	for _, f := range flags {
+		line := "complete -c gols"
+		if f.short != "" {
+			line += " -s " + fishQuote(f.short)
+		}
+		if f.long != "" {
+			line += " -l " + f.long
+		}
+		if f.value == requiredValue {
+			line += " -r"
+		}
+
+		switch {
+		case f.values != nil:
+			line += " -f -a " + fishQuote(strings.Join(f.values(), " "))
+		case f.complete == completeExtensions:
+			line += " -f -a '(__gols_extensions)'"
+		case f.complete == completeDepth:
+			line += " -f -a " + fishQuote(strings.Join(completionDepths, " "))
+		case f.complete == completeFile:
+			line += " -F"
+		case f.complete == completeDir:
+			line += " -f -a '(__fish_complete_directories)'"
+		case f.complete == completeUser:
+			line += " -f -a '(__fish_complete_users)'"
+		case f.complete == completeGroup:
+			line += " -f -a '(__fish_complete_groups)'"
+		}
+		fmt.Fprintf(w, "%s -d %s\n", line, fishQuote(f.desc))
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/elbachir-one/gols"
+)
+
+// The standard library has no xz or zstd decoder, so the command reads
+// those archives through the usual command line tools.
+func init() {
+	gols.RegisterDecompressor("xz", toolDecompressor("xz"))
+	gols.RegisterDecompressor("zstd", toolDecompressor("zstd"))
+}
+
+// toolDecompressor decompresses with tool -dc, as tar does.
+func toolDecompressor(tool string) gols.Decompressor {
+	return func(r io.Reader) (io.ReadCloser, error) {
+		if _, err := exec.LookPath(tool); err != nil {
+			return nil, fmt.Errorf("reading %s archives needs the %s command", tool, tool)
+		}
+		cmd := exec.Command(tool, "-dc")
+		cmd.Stdin = r
+		out, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		t := &toolReader{ReadCloser: out, cmd: cmd}
+		cmd.Stderr = &t.stderr
+		if err := cmd.Start(); err != nil {
+			return nil, err
+		}
+		return t, nil
+	}
+}
+
+// toolReader reads the output of a decompressing tool.
+type toolReader struct {
+	io.ReadCloser
+	cmd    *exec.Cmd
+	stderr bytes.Buffer
+	eof    bool
+}
+
+func (t *toolReader) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	if err == io.EOF {
+		t.eof = true
+	}
+	return n, err
+}
+
+// Close stops the tool, which may not have written everything when the
+// archive was not read to the end. Once it was, the tool has exited and
+// its status tells whether the archive was corrupt.
+func (t *toolReader) Close() error {
+	if !t.eof {
+		t.cmd.Process.Kill()
+		t.cmd.Wait()
+		return nil
+	}
+	if err := t.cmd.Wait(); err != nil {
+		if message := strings.TrimSpace(t.stderr.String()); message != "" {
+			return fmt.Errorf("%s: %s", t.cmd.Args[0], message)
+		}
+		return fmt.Errorf("%s: %v", t.cmd.Args[0], err)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestToolDecompressor(t *testing.T) {
+	if _, err := exec.LookPath("xz"); err != nil {
+		t.Skip("xz is not installed")
+	}
+	cmd := exec.Command("xz", "-c")
+	cmd.Stdin = strings.NewReader("archive contents")
+	compressed, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, input string
+		read        bool
+		want        string
+		err         bool
+	}{
+		{name: "read", input: string(compressed), read: true, want: "archive contents"},
+		{name: "corrupt", input: "not xz data", read: true, err: true},
+		{name: "truncated", input: string(compressed[:len(compressed)/2]), read: true, err: true},
+		{name: "unread", input: string(compressed)},
+	}
+	for _, test := range tests {
+		rc, err := toolDecompressor("xz")(strings.NewReader(test.input))
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		var got bytes.Buffer
+		if test.read {
+			io.Copy(&got, rc)
+		}
+		err = rc.Close()
+		if (err != nil) != test.err {
+			t.Errorf("%s: Close = %v, want an error: %v", test.name, err, test.err)
+		}
+		if !test.err && got.String() != test.want {
+			t.Errorf("%s: read %q, want %q", test.name, got.String(), test.want)
+		}
+	}
+}
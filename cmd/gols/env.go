@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// optsEnv names the variable that holds default arguments, parsed like
+// the command line and applied before it.
+const optsEnv = "GOLS_OPTS"
+
+// parseEnvFlags applies the flags of $GOLS_OPTS. It reports whether
+// there were any, and whether any of them were specific flags, as
+// parseFlags does.
+func parseEnvFlags() (bool, bool) {
+	args, err := splitArgs(os.Getenv(optsEnv))
+	if err != nil {
+		fmt.Printf("Invalid %s: %v\n", optsEnv, err)
+		os.Exit(1)
+	}
+	nonFlagArgs, hasFlags, hasSpecificFlags := parseFlags(args)
+	if len(nonFlagArgs) > 0 {
+		fmt.Printf("Invalid %s: %s is not a flag\n", optsEnv, nonFlagArgs[0])
+		os.Exit(1)
+	}
+	return hasFlags, hasSpecificFlags
+}
+
+// splitArgs splits s into words the way a POSIX shell would, without
+// expanding anything: words are separated by blanks, single quotes
+// keep everything, and double quotes keep everything but a backslash
+// before ", \, $ or `.
+func splitArgs(s string) ([]string, error) {
+	var args []string
+	var word strings.Builder
+	inWord := false
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				args = append(args, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case c == '\\':
+			inWord = true
+			if i+1 < len(s) {
+				i++
+				if s[i] != '\n' {
+					word.WriteByte(s[i])
+				}
+			}
+		case c == '\'':
+			inWord = true
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated single quote")
+			}
+			word.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+		case c == '"':
+			inWord = true
+			closed := false
+			for i++; i < len(s); i++ {
+				if s[i] == '"' {
+					closed = true
+					break
+				}
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("\"\\$`", s[i+1]) >= 0 {
+					i++
+				}
+				word.WriteByte(s[i])
+			}
+			if !closed {
+				return nil, errors.New("unterminated double quote")
+			}
+		default:
+			inWord = true
+			word.WriteByte(c)
+		}
+	}
+	if inWord {
+		args = append(args, word.String())
+	}
+	return args, nil
+}
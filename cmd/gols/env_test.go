@@ -0,0 +1,43 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+		err  string
+	}{
+		{in: "", want: nil},
+		{in: "  \t\n", want: nil},
+		{in: "-l -a", want: []string{"-l", "-a"}},
+		{in: "  -l\t--tree-style=ascii\n", want: []string{"-l", "--tree-style=ascii"}},
+		{in: `--ignore '*.o' --match "src/**"`, want: []string{"--ignore", "*.o", "--match", "src/**"}},
+		{in: `'it''s'`, want: []string{"its"}},
+		{in: `'a "b" \c'`, want: []string{`a "b" \c`}},
+		{in: `"a \"b\" \\ \$x \c"`, want: []string{`a "b" \ $x \c`}},
+		{in: `a\ b c\\d`, want: []string{"a b", `c\d`}},
+		{in: "a\\\nb", want: []string{"ab"}},
+		{in: `x"y"'z'`, want: []string{"xyz"}},
+		{in: `'' ""`, want: []string{"", ""}},
+		{in: `trailing\`, want: []string{"trailing"}},
+		{in: `'open`, err: "unterminated single quote"},
+		{in: `"open`, err: "unterminated double quote"},
+		{in: `"ends with \"`, err: "unterminated double quote"},
+	}
+	for _, test := range tests {
+		got, err := splitArgs(test.in)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("splitArgs(%q) error = %v, want %q", test.in, err, test.err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("splitArgs(%q) = %q, %v, want %q", test.in, got, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/elbachir-one/gols"
+)
+
+// flagValue tells whether a flag takes a value, and how it is given.
+type flagValue int
+
+const (
+	noValue       flagValue = iota
+	requiredValue           // -d 2, --size +1M or --size=+1M
+	optionalValue           // only with =, as in --gitignore=dim
+)
+
+// What the value of a flag completes to, besides a fixed list of values.
+const (
+	completeExtensions = "extensions" // extensions present in the listed directory
+	completeDepth      = "depth"
+	completeFile       = "file"
+	completeDir        = "dir"
+	completeUser       = "user"
+	completeGroup      = "group"
+)
+
+// flagDef describes a flag for --help and the completion scripts.
+// parseFlags does the parsing itself; TestParseFlagsMatchesTable keeps
+// the two in step.
+type flagDef struct {
+	short string // "a" for -a, if the flag has a short form
+	long  string // "summary-only" for --summary-only, if it has a long form
+	value flagValue
+
+	complete string          // one of the complete constants
+	values   func() []string // the values the flag accepts, when they are fixed
+
+	usage string // left column of --help, empty to leave the flag out
+	desc  string
+}
+
+type helpSection struct {
+	title string // empty to continue the previous section after a blank line
+	width int    // of the usage column
+	flags []flagDef
+}
+
+func fixedValues(values ...string) func() []string {
+	return func() []string { return values }
+}
+
+var helpSections = []helpSection{
+	{title: "FLAGS", width: 20, flags: []flagDef{
+		{short: "?", long: "help", usage: "-? --help", desc: "Help"},
+	}},
+	{width: 20, flags: []flagDef{
+		{short: "a", usage: "-a", desc: "Show Hidden files"},
+		{short: "A", usage: "-A", desc: "Show only hidden files and directories"},
+		{short: "e", value: requiredValue, complete: completeExtensions, usage: "-e", desc: "Filter files based on extensions"},
+		{short: "f", usage: "-f", desc: "Show summary of directories and files"},
+		{short: "F", usage: "-F", desc: "List files only"},
+		{short: "c", usage: "-c", desc: "Don't use spacing, print all files in one column"},
+		{short: "D", usage: "-D", desc: "Only directories are showing"},
+		{short: "g", usage: "-g", desc: "Show the group of entries only"},
+		{short: "h", usage: "-h", desc: "Human-readable file sizes"},
+		{short: "i", usage: "-i", desc: "Show directory icon on left"},
+		{short: "l", usage: "-l", desc: "Long listing format"},
+		{short: "m", usage: "-m", desc: "Only symbolic links are showing"},
+		{short: "o", usage: "-o", desc: "Sort by size"},
+		{short: "O", usage: "-O", desc: "Show the owner of entries only"},
+		{short: "p", usage: "-p", desc: "Show the permissions of entries only"},
+		{short: "r", usage: "-r d n", desc: "Tree like listing, set the depth of the directory tree (n is an integer)"},
+		{short: "d", value: requiredValue, complete: completeDepth, desc: "Depth of the directory tree"},
+		{long: "tree-style", value: requiredValue, values: fixedValues("unicode", "ascii", "rounded", "none"), usage: "--tree-style=S", desc: "Draw -r with unicode (default), ascii, rounded or no guides"},
+		{long: "collapse", usage: "--collapse", desc: "With -r, join directories that hold a single directory: a/b/c"},
+		{long: "filelimit", value: requiredValue, usage: "--filelimit N", desc: "With -r, don't enter directories of more than N entries"},
+		{long: "hidden-count", usage: "--hidden-count", desc: "With -r, show how many hidden entries each directory has"},
+		{short: "L", long: "follow", usage: "-L --follow", desc: "With -r, enter symlinked directories, but not ones that loop back"},
+		{long: "one-file-system", usage: "--one-file-system", desc: "With -r, don't enter directories on other file systems"},
+		{short: "R", long: "recursive-flat", usage: "-R", desc: "List every directory of the tree in its own section, like ls -R"},
+		{short: "s", usage: "-s", desc: "Print files size"},
+		{short: "t", usage: "-t", desc: "Order by time"},
+		{short: "T", usage: "-T", desc: "Show the modification time of entries only"},
+		{short: "v", long: "version", usage: "-v --version", desc: "Show version"},
+		{long: "summary-only", usage: "--summary-only", desc: "Print the -f summary without the listing"},
+		{long: "du", usage: "--du", desc: "With -s or -l, show directories with the total size of their contents"},
+		{long: "bars", usage: "--bars", desc: "Draw a bar next to each size with -s or -l, scaled to the largest file"},
+		{long: "format", value: requiredValue, values: gols.Renderers, usage: "--format=NAME", desc: "Render with grid, oneline, long, tree, json or a registered renderer"},
+		{long: "no-hidden", usage: "--no-hidden", desc: "Undo -a and -A, for a default from GOLS_OPTS"},
+		{long: "no-icons", usage: "--no-icons", desc: "Names without icons"},
+		{short: "x", value: requiredValue, complete: completeExtensions, usage: "-x", desc: "Exclude specific extensions"},
+	}},
+	{title: "FILTERS", width: 28, flags: []flagDef{
+		{long: "size", value: requiredValue, usage: "--size [+-]N[kMGT]", desc: "Size more than (+), less than (-) or exactly N"},
+		{long: "newer", value: requiredValue, complete: completeFile, usage: "--newer AGE|DATE|FILE", desc: "Modified more recently than 2d, 3h, 2024-01-31 or FILE"},
+		{long: "newer-than", value: requiredValue, complete: completeFile, desc: "Modified more recently than 2d, 3h, 2024-01-31 or FILE"},
+		{long: "older", value: requiredValue, complete: completeFile, desc: "Modified before 2d, 3h, 2024-01-31 or FILE"},
+		{long: "older-than", value: requiredValue, complete: completeFile, usage: "--older-than AGE|DATE|FILE", desc: "Modified before 2d, 3h, 2024-01-31 or FILE"},
+		{long: "user", value: requiredValue, complete: completeUser, usage: "--user NAME|UID", desc: "Owned by user"},
+		{long: "group", value: requiredValue, complete: completeGroup, usage: "--group NAME|GID", desc: "Owned by group"},
+		{long: "perm", value: requiredValue, usage: "--perm [-/]MODE", desc: "Permissions exactly (644), all of (-u+x) or any of (/o+w)"},
+		{long: "empty", usage: "--empty", desc: "Empty files and directories"},
+		{long: "match", value: requiredValue, usage: "--match GLOB", desc: "Names or paths matching GLOB, ** spans directories"},
+		{long: "regex", value: requiredValue, usage: "--regex RE", desc: "Names matching the regular expression RE"},
+		{long: "ignore", value: requiredValue, usage: "--ignore GLOB", desc: "Leave out names or paths matching GLOB (repeatable)"},
+		{long: "ignore-case", usage: "--ignore-case", desc: "Match extensions, globs and regexes without case"},
+		{long: "gitignore", value: optionalValue, values: fixedValues("hide", "dim"), usage: "--gitignore[=hide|dim]", desc: "Hide entries ignored by git, or show them dimmed"},
+	}},
+	{title: "GIT", width: 28, flags: []flagDef{
+		{long: "git", usage: "--git", desc: "Show the git status of entries and the branch of repositories"},
+		{long: "git-log", usage: "--git-log", desc: "Long listing with the date, hash and author of the last commit"},
+	}},
+	{title: "CONTENT", width: 28, flags: []flagDef{
+		{long: "sniff", usage: "--sniff", desc: "Pick icons from file contents when the extension has none"},
+		{long: "kind", usage: "--kind", desc: "Show the detected file type in a column (implies --sniff)"},
+	}},
+	{title: "CHECKSUMS", width: 28, flags: []flagDef{
+		{long: "checksum", value: optionalValue, values: fixedValues("sha256", "sha512", "sha1", "md5", "blake2b", "crc32"), usage: "--checksum[=ALGORITHM]", desc: "Digest column: sha256 (default), sha512, sha1, md5, blake2b or crc32"},
+		{long: "verify", value: requiredValue, complete: completeFile, usage: "--verify SUMSFILE", desc: "Check files against a sha256sum-style file: OK, CHANGED or MISSING"},
+	}},
+	{title: "MODES", width: 28, flags: []flagDef{
+		{long: "find", value: requiredValue, usage: "--find PATTERN", desc: "Search the tree for names containing PATTERN or matching it as a glob"},
+		{long: "diff", usage: "--diff DIR_A DIR_B", desc: "Show entries that differ between two directories, as a tree with -r"},
+		{long: "snapshot", value: requiredValue, complete: completeFile, usage: "--snapshot FILE", desc: "Record the metadata of the listing, or the tree with -r, as JSON"},
+		{long: "since", value: requiredValue, complete: completeFile, usage: "--since FILE", desc: "Show entries added, removed or changed since a --snapshot"},
+		{long: "dupes", usage: "--dupes", desc: "Find files with identical contents in the tree"},
+		{long: "tui", usage: "--tui", desc: "Interactive browser, prints the chosen path on exit"},
+		{long: "watch", usage: "--watch", desc: "Keep running and redraw the listing when entries change"},
+		{long: "completion", value: requiredValue, values: completionShells, usage: "--completion SHELL", desc: "Print the completion script for bash, zsh or fish"},
+	}},
+}
+
+// allFlags returns the flags of every help section.
+func allFlags() []flagDef {
+	var flags []flagDef
+	for _, section := range helpSections {
+		flags = append(flags, section.flags...)
+	}
+	return flags
+}
+
+func showHelp() {
+	fmt.Println()
+	fmt.Println("Usage: gols [FLAG] [DIRECTORY] [FILES]")
+	fmt.Println()
+	fmt.Println("Flags in $GOLS_OPTS come first; when flags conflict the last one wins.")
+	fmt.Println()
+	for _, section := range helpSections {
+		if section.title != "" {
+			fmt.Println(section.title + ":")
+			fmt.Println()
+		}
+		for _, flag := range section.flags {
+			if flag.usage != "" {
+				fmt.Printf("\t%-*s%s\n", section.width, flag.usage, flag.desc)
+			}
+		}
+		fmt.Println()
+	}
+}
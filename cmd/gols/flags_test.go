@@ -0,0 +1,136 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/elbachir-one/gols"
+)
+
+// parsedFlags returns the flags parseFlags has a case for, read from
+// the source of main.go.
+func parsedFlags(t *testing.T) map[string]bool {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), "main.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flags := map[string]bool{}
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Name.Name != "parseFlags" {
+			continue
+		}
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			clause, ok := n.(*ast.CaseClause)
+			if !ok {
+				return true
+			}
+			for _, expr := range clause.List {
+				lit, ok := expr.(*ast.BasicLit)
+				if !ok {
+					continue
+				}
+				switch lit.Kind {
+				case token.STRING:
+					name, err := strconv.Unquote(lit.Value)
+					if err == nil && strings.HasPrefix(name, "--") {
+						flags[name] = true
+					}
+				case token.CHAR:
+					letter, _, _, err := strconv.UnquoteChar(lit.Value[1:len(lit.Value)-1], '\'')
+					if err == nil {
+						flags["-"+string(letter)] = true
+					}
+				}
+			}
+			return true
+		})
+	}
+	if len(flags) == 0 {
+		t.Fatal("no flags found in parseFlags")
+	}
+	return flags
+}
+
+func TestParseFlagsMatchesTable(t *testing.T) {
+	// Flags the completion scripts call gols with, not meant for users.
+	internal := map[string]bool{"--complete-ext": true}
+
+	parsed := parsedFlags(t)
+	table := map[string]bool{}
+	for _, flag := range allFlags() {
+		for _, name := range flag.flagNames() {
+			table[name] = true
+		}
+	}
+
+	for name := range parsed {
+		if !table[name] && !internal[name] {
+			t.Errorf("%s is parsed but missing from helpSections", name)
+		}
+	}
+	for name := range table {
+		if !parsed[name] {
+			t.Errorf("%s is in helpSections but not parsed", name)
+		}
+	}
+}
+
+func TestValueFlags(t *testing.T) {
+	shorts, longs := valueFlags(allFlags())
+	for _, want := range []string{"d", "e", "x"} {
+		if !strings.Contains(shorts, want) {
+			t.Errorf("short value flags %q lack %s", shorts, want)
+		}
+	}
+	for _, flag := range []string{"--size", "--find", "--tree-style"} {
+		found := false
+		for _, long := range longs {
+			found = found || long == flag
+		}
+		if !found {
+			t.Errorf("long value flags %v lack %s", longs, flag)
+		}
+	}
+	for _, long := range longs {
+		if long == "--gitignore" || long == "--checksum" {
+			t.Errorf("%s only takes a value after =, but is in %v", long, longs)
+		}
+	}
+}
+
+// TestParseFlagsModes checks that the flags picking how entries are
+// shown undo each other, so that the last one given wins.
+func TestParseFlagsModes(t *testing.T) {
+	tests := []struct {
+		args                   []string
+		long, oneColumn, sizes bool
+	}{
+		{args: []string{"--git-log"}, long: true},
+		{args: []string{"-s", "--git-log"}, long: true},
+		{args: []string{"--git-log", "-s"}, sizes: true},
+		{args: []string{"--checksum"}, oneColumn: true},
+		{args: []string{"-s", "--checksum"}, oneColumn: true},
+		{args: []string{"--checksum", "-s"}, sizes: true},
+		{args: []string{"-l", "--checksum"}, long: true},
+		{args: []string{"--checksum", "-l"}, long: true},
+		{args: []string{"-s", "--kind"}, oneColumn: true},
+		{args: []string{"--kind", "-s"}, sizes: true},
+		{args: []string{"-l", "--kind"}, long: true},
+	}
+	defer func() { opts = gols.DefaultOptions() }()
+	for _, test := range tests {
+		opts = gols.DefaultOptions()
+		parseFlags(test.args)
+		if opts.Long != test.long || opts.OneColumn != test.oneColumn || opts.Sizes != test.sizes {
+			t.Errorf("%v: long %v, one column %v, sizes %v, want %v, %v, %v", test.args,
+				opts.Long, opts.OneColumn, opts.Sizes, test.long, test.oneColumn, test.sizes)
+		}
+	}
+}
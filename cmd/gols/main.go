@@ -0,0 +1,566 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/elbachir-one/gols"
+)
+
+// opts collects the listing options given on the command line; the
+// other variables select what gols does with them.
+var (
+	opts         = gols.DefaultOptions()
+	showVersion  bool
+	extFlag      string
+	tuiMode      bool
+	watchMode    bool
+	findMode     bool
+	findPattern  string
+	dupesMode    bool
+	diffMode     bool
+	verifyFile   string
+	snapshotFile string
+	sinceFile    string
+
+	completionShell string
+	completeExtDir  string // --complete-ext, used by the completion scripts
+)
+
+func main() {
+	envHasFlags, envHasSpecificFlags := parseEnvFlags()
+	nonFlagArgs, hasFlags, hasSpecificFlags := parseFlags(os.Args[1:])
+	hasFlags = hasFlags || envHasFlags
+	hasSpecificFlags = hasSpecificFlags || envHasSpecificFlags
+
+	if showVersion {
+		fmt.Println(gols.Version)
+		return
+	}
+
+	if completionShell != "" {
+		printCompletion(os.Stdout, completionShell)
+		return
+	}
+	if completeExtDir != "" {
+		printExtensions(os.Stdout, completeExtDir)
+		return
+	}
+
+	var directory string
+
+	if len(nonFlagArgs) > 0 {
+		directory = nonFlagArgs[0]
+	}
+
+	if diffMode {
+		if len(nonFlagArgs) != 2 {
+			fmt.Println("--diff needs two directories")
+			os.Exit(1)
+		}
+		if err := opts.Validate(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		same, err := gols.Diff(os.Stdout, nonFlagArgs[0], nonFlagArgs[1], opts)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if !same {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(nonFlagArgs) > 1 {
+		opts.Extensions = strings.Split(nonFlagArgs[1], ",")
+	} else if extFlag != "" {
+		opts.Extensions = strings.Split(extFlag, ",")
+	}
+
+	if err := opts.Validate(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if directory == "" {
+		directory = "."
+	}
+
+	if tuiMode {
+		// The browser draws on the terminal, leaving stdout for the
+		// path it prints.
+		tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+		if err != nil {
+			log.Fatalf("Error: --tui needs a terminal: %v", err)
+		}
+		selected, err := gols.Browse(tty, directory, opts)
+		tty.Close()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if selected != "" {
+			fmt.Println(selected)
+		}
+		return
+	}
+
+	if findMode {
+		found, err := gols.Find(os.Stdout, directory, findPattern, opts)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if !found {
+			fmt.Println("No files found.")
+		}
+		return
+	}
+
+	if dupesMode {
+		found, err := gols.Dupes(os.Stdout, directory, opts)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if !found {
+			fmt.Println("No duplicates found.")
+		}
+		return
+	}
+
+	if snapshotFile != "" {
+		var buf bytes.Buffer
+		count, err := gols.WriteSnapshot(&buf, directory, opts)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if err := os.WriteFile(snapshotFile, buf.Bytes(), 0644); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		root, _ := filepath.Abs(directory)
+		fmt.Printf("Recorded \033[32m%d\033[0m entries of %s in %s\n", count, root, snapshotFile)
+		return
+	}
+
+	if sinceFile != "" {
+		root := ""
+		if len(nonFlagArgs) > 0 {
+			root = directory
+		}
+		f, err := os.Open(sinceFile)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		unchanged, err := gols.Since(os.Stdout, f, root, opts)
+		f.Close()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if !unchanged {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if verifyFile != "" {
+		ok, err := gols.Verify(os.Stdout, verifyFile, directory, opts)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if !ok {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if watchMode {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if err := gols.Watch(ctx, os.Stdout, directory, opts); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
+
+	found, err := gols.Print(os.Stdout, directory, opts)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	if !found {
+		return
+	}
+
+	// The grid ends its last row itself; the other renderers get a
+	// blank line after them, as they always have.
+	if ((hasSpecificFlags && !opts.Long) || !hasFlags) && opts.FormatName() != "grid" {
+		fmt.Println()
+	}
+}
+
+// setMode picks how entries are shown: the flags that pick one undo
+// each other, so that the last one given wins.
+func setMode(mode *bool) {
+	opts.Long, opts.Sizes, opts.OneColumn = false, false, false
+	opts.PermissionsOnly, opts.OwnerOnly, opts.TimeOnly, opts.GroupOnly = false, false, false, false
+	opts.Format = ""
+	*mode = true
+}
+
+// setColumnMode picks the one-per-line output for the flags that add a
+// column to it, unless the long listing, which shows the column too, is
+// the mode given last.
+func setColumnMode() {
+	if !opts.Long {
+		setMode(&opts.OneColumn)
+	}
+}
+
+// setType limits the listing to one type of entry, the last one given.
+func setType(only *bool) {
+	opts.DirsOnly, opts.FilesOnly, opts.SymlinksOnly = false, false, false
+	*only = true
+}
+
+func parseFlags(args []string) ([]string, bool, bool) {
+	var nonFlagArgs []string
+	hasFlags := false
+	hasSpecificFlags := false
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		if len(arg) > 1 && arg[0] == '-' {
+			hasFlags = true
+			if len(arg) > 2 && arg[1] == '-' {
+				name, value, hasValue := strings.Cut(arg, "=")
+				switch name {
+				case "--version":
+					showVersion = true
+				case "--help":
+					showHelp()
+					os.Exit(0)
+				case "--size", "--newer", "--older", "--newer-than", "--older-than", "--user", "--group", "--perm":
+					if !hasValue {
+						if i+1 >= len(args) {
+							fmt.Println("Missing value for", name)
+							os.Exit(1)
+						}
+						value = args[i+1]
+						i++
+					}
+					var pred gols.Predicate
+					var err error
+					switch name {
+					case "--size":
+						pred, err = gols.SizePredicate(value)
+					case "--newer", "--newer-than":
+						pred, err = gols.NewerPredicate(value)
+					case "--older", "--older-than":
+						pred, err = gols.OlderPredicate(value)
+					case "--user":
+						pred, err = gols.UserPredicate(value)
+					case "--group":
+						pred, err = gols.GroupPredicate(value)
+					case "--perm":
+						pred, err = gols.PermPredicate(value)
+					}
+					if err != nil {
+						fmt.Printf("Invalid value for %s: %v\n", name, err)
+						os.Exit(1)
+					}
+					opts.Predicates = append(opts.Predicates, pred)
+					hasSpecificFlags = true
+				case "--empty":
+					opts.Predicates = append(opts.Predicates, gols.EmptyPredicate())
+					hasSpecificFlags = true
+				case "--format":
+					if !hasValue {
+						if i+1 >= len(args) {
+							fmt.Println("Missing value for", name)
+							os.Exit(1)
+						}
+						value = args[i+1]
+						i++
+					}
+					if _, found := gols.LookupRenderer(value); !found {
+						fmt.Printf("Invalid value for --format: %s (one of %s)\n", value, strings.Join(gols.Renderers(), ", "))
+						os.Exit(1)
+					}
+					opts.Format = value
+				case "--completion", "--complete-ext":
+					if !hasValue {
+						if i+1 >= len(args) {
+							fmt.Println("Missing value for", name)
+							os.Exit(1)
+						}
+						value = args[i+1]
+						i++
+					}
+					if name == "--complete-ext" {
+						completeExtDir = value
+						break
+					}
+					if _, found := completionScripts[value]; !found {
+						fmt.Printf("Invalid value for --completion: %s (one of %s)\n", value, strings.Join(completionShells(), ", "))
+						os.Exit(1)
+					}
+					completionShell = value
+				case "--find":
+					if !hasValue {
+						if i+1 >= len(args) {
+							fmt.Println("Missing value for", name)
+							os.Exit(1)
+						}
+						value = args[i+1]
+						i++
+					}
+					findMode = true
+					findPattern = value
+				case "--match", "--regex", "--ignore":
+					if !hasValue {
+						if i+1 >= len(args) {
+							fmt.Println("Missing value for", name)
+							os.Exit(1)
+						}
+						value = args[i+1]
+						i++
+					}
+					switch name {
+					case "--match":
+						opts.Match = append(opts.Match, value)
+					case "--regex":
+						opts.Regex = append(opts.Regex, value)
+					case "--ignore":
+						opts.Ignore = append(opts.Ignore, value)
+					}
+					hasSpecificFlags = true
+				case "--ignore-case":
+					opts.IgnoreCase = true
+				case "--checksum":
+					if !hasValue {
+						value = "sha256"
+					}
+					opts.Checksum = value
+					setColumnMode()
+				case "--verify", "--snapshot", "--since":
+					if !hasValue {
+						if i+1 >= len(args) {
+							fmt.Println("Missing value for", name)
+							os.Exit(1)
+						}
+						value = args[i+1]
+						i++
+					}
+					switch name {
+					case "--verify":
+						verifyFile = value
+					case "--snapshot":
+						snapshotFile = value
+					case "--since":
+						sinceFile = value
+					}
+				case "--summary-only":
+					opts.Summary = true
+					opts.SummaryOnly = true
+				case "--recursive-flat":
+					opts.Recursive, opts.RecursiveFlat = false, true
+				case "--tree-style", "--filelimit":
+					if !hasValue {
+						if i+1 >= len(args) {
+							fmt.Println("Missing value for", name)
+							os.Exit(1)
+						}
+						value = args[i+1]
+						i++
+					}
+					if name == "--tree-style" {
+						opts.TreeStyle = value
+						break
+					}
+					limit, err := strconv.Atoi(value)
+					if err != nil || limit < 0 {
+						fmt.Println("Invalid value for --filelimit:", value)
+						os.Exit(1)
+					}
+					opts.FileLimit = limit
+				case "--follow":
+					opts.Follow = true
+				case "--one-file-system":
+					opts.OneFileSystem = true
+				case "--collapse":
+					opts.Collapse = true
+				case "--hidden-count":
+					opts.HiddenCount = true
+				case "--du":
+					opts.DirSizes = true
+				case "--bars":
+					opts.Bars = true
+				case "--diff":
+					diffMode = true
+				case "--sniff":
+					opts.Sniff = true
+				case "--kind":
+					opts.Sniff = true
+					opts.Kind = true
+					setColumnMode()
+				case "--dupes":
+					dupesMode = true
+				case "--watch":
+					watchMode = true
+				case "--tui":
+					tuiMode = true
+				case "--git":
+					opts.GitStatus = true
+					hasSpecificFlags = true
+				case "--git-log":
+					opts.GitLog = true
+					setMode(&opts.Long)
+				case "--no-hidden":
+					opts.All, opts.HiddenOnly = false, false
+					hasSpecificFlags = true
+				case "--no-icons":
+					opts.NoIcons = true
+				case "--gitignore":
+					switch {
+					case !hasValue:
+						opts.Gitignore = "hide"
+					case value == "hide" || value == "dim":
+						opts.Gitignore = value
+					default:
+						fmt.Println("Invalid value for --gitignore:", value)
+						os.Exit(1)
+					}
+					hasSpecificFlags = true
+				default:
+					fmt.Println("Unknown long flag:", arg)
+					showHelp()
+					os.Exit(1)
+				}
+			} else {
+
+				for j := 1; j < len(arg); j++ {
+					switch arg[j] {
+					case 'l':
+						setMode(&opts.Long)
+					case 'c':
+						setMode(&opts.OneColumn)
+					case 'h':
+						opts.HumanReadable = true
+						hasSpecificFlags = true
+					case 'g':
+						setMode(&opts.GroupOnly)
+					case 's':
+						setMode(&opts.Sizes)
+					case 'o':
+						opts.SortBySize, opts.SortByTime = true, false
+						hasSpecificFlags = true
+					case 'p':
+						setMode(&opts.PermissionsOnly)
+					case 'O':
+						setMode(&opts.OwnerOnly)
+					case 't':
+						opts.SortBySize, opts.SortByTime = false, true
+						hasSpecificFlags = true
+					case 'T':
+						setMode(&opts.TimeOnly)
+					case 'm':
+						setType(&opts.SymlinksOnly)
+						hasSpecificFlags = true
+					case 'a':
+						opts.All, opts.HiddenOnly = true, false
+						hasSpecificFlags = true
+					case 'A':
+						opts.All, opts.HiddenOnly = true, true
+						hasSpecificFlags = true
+					case 'r':
+						opts.Recursive, opts.RecursiveFlat = true, false
+					case 'R':
+						opts.Recursive, opts.RecursiveFlat = false, true
+					case 'L':
+						opts.Follow = true
+					case 'i':
+						opts.DirIconLeft = true
+						hasSpecificFlags = true
+						hasFlags = true
+					case 'f':
+						opts.Summary = true
+						hasSpecificFlags = true
+					case 'v':
+						showVersion = true
+					case '?':
+						showHelp()
+						os.Exit(0)
+					case 'D':
+						setType(&opts.DirsOnly)
+						hasSpecificFlags = true
+					case 'F':
+						setType(&opts.FilesOnly)
+						hasSpecificFlags = true
+					case 'x':
+						if j+1 < len(arg) && (arg[j+1] < '0' || arg[j+1] > '9') {
+							opts.ExcludeExtensions = strings.Split(arg[j+1:], ",")
+							hasSpecificFlags = true
+							break
+						} else if i+1 < len(args) && args[i+1][0] != '-' {
+							opts.ExcludeExtensions = strings.Split(args[i+1], ",")
+							hasSpecificFlags = true
+							i++
+							break
+						} else {
+							fmt.Println("Missing value for -x")
+							os.Exit(1)
+						}
+					case 'd':
+						if j+1 < len(arg) && arg[j+1] >= '0' && arg[j+1] <= '9' {
+							depthValue := arg[j+1:]
+							maxDepthValue, err := strconv.Atoi(depthValue)
+							if err != nil {
+								fmt.Println("Invalid value for -d")
+								os.Exit(1)
+							}
+							opts.MaxDepth = maxDepthValue
+							hasSpecificFlags = true
+							break
+						} else if i+1 < len(args) {
+							depthValue := args[i+1]
+							maxDepthValue, err := strconv.Atoi(depthValue)
+							if err != nil {
+								fmt.Println("Invalid value for -d")
+								os.Exit(1)
+							}
+							opts.MaxDepth = maxDepthValue
+							hasSpecificFlags = true
+							i++
+							break
+						} else {
+							fmt.Println("Missing value for -d")
+							os.Exit(1)
+						}
+					case 'e':
+						if i+1 < len(args) {
+							extFlag = args[i+1]
+							i++
+							hasSpecificFlags = true
+						} else {
+							fmt.Println("Missing value for -e")
+							os.Exit(1)
+						}
+					default:
+						showHelp()
+						os.Exit(1)
+					}
+				}
+			}
+		} else {
+			nonFlagArgs = append(nonFlagArgs, arg)
+		}
+	}
+	return nonFlagArgs, hasFlags, hasSpecificFlags
+}
@@ -1,7 +1,13 @@
-package main
+package gols
+
+import (
+	"io/fs"
+	"path/filepath"
+)
 
 const (
-	version = "gols: 1.4.4"
+	// Version is what gols -v prints.
+	Version = "gols: 1.4.4"
 )
 
 const (
@@ -48,8 +54,11 @@ const (
 	darkBlue      = "\033[38;5;33m"
 )
 
+// FileIcons maps extensions, DirectoryIcons directory names and
+// SpecialFileIcons whole file names to icons. They can be extended before
+// listing to add icons of your own.
 var (
-	fileIcons = map[string]string{
+	FileIcons = map[string]string{
 		".go":        " ",
 		".mod":       " ",
 		".sh":        " ",
@@ -171,7 +180,7 @@ var (
         ".sig":       " ",
 	}
 
-    directoryIcons = map[string]string{
+    DirectoryIcons = map[string]string{
         "default":      "",
         "Music":        "󱍙",
         "Downloads":    "󰉍",
@@ -213,7 +222,7 @@ var (
         ".wine":        "󰡶",
     }
 
-	specialFileIcons = map[string]string{
+	SpecialFileIcons = map[string]string{
 		"default":        white + "󱁹 " + reset,
 		"Makefile":       darkBlue + " " + reset,
 		"Dockerfile":     blue + " " + reset,
@@ -251,3 +260,159 @@ var (
 	iconSymlinkDir  = "\033[38;5;198m \033[0m"
 	iconSymlinkFile = "\033[36m \033[0m"
 )
+
+// withIcon puts icon in front of name, separated by a space, unless
+// --no-icons turned icons off.
+func (ls *lister) withIcon(icon, name string) string {
+	if ls.opts.NoIcons {
+		return name
+	}
+	return icon + " " + name
+}
+
+// DirectoryIcon returns the icon for a directory, chosen by its name.
+func DirectoryIcon(directory string) string {
+	for dirType, icon := range DirectoryIcons {
+		if filepath.Base(directory) == dirType {
+			return icon
+		}
+	}
+	return DirectoryIcons["default"]
+}
+
+// SpecialFileIcon returns the icon for well-known file names such as
+// Makefile or go.mod, which take precedence over the extension.
+func SpecialFileIcon(fileName string) (string, bool) {
+	icon, found := SpecialFileIcons[fileName]
+	return icon, found
+}
+
+// FileIcon returns the coloured icon gols shows in front of file, an
+// entry of directory: symlinks by what they point to, directories by
+// name, then special names and extensions. Unlike Entry.Icon with
+// Options.Sniff, it never reads the contents of the file.
+func FileIcon(file fs.DirEntry, directory string) string {
+	info, err := file.Info()
+	if err != nil {
+		return iconOther
+	}
+	return defaultLister().iconFor(file, info.Mode(), directory, false)
+}
+
+func (ls *lister) getFileIcon(file fs.DirEntry, mode fs.FileMode, directory string) string {
+	if ls.opts.NoIcons {
+		return ""
+	}
+	return ls.iconFor(file, mode, directory, ls.sniff)
+}
+
+func (ls *lister) iconFor(file fs.DirEntry, mode fs.FileMode, directory string, sniff bool) string {
+	if file.Type()&fs.ModeSymlink != 0 {
+		linkTarget, err := ls.readLink(filepath.Join(directory, file.Name()))
+		if err == nil {
+			symlinkTarget := filepath.Join(directory, linkTarget)
+			targetInfo, err := ls.statPath(symlinkTarget)
+			if err == nil && targetInfo.IsDir() {
+				return iconSymlinkDir
+			} else {
+				return iconSymlinkFile
+			}
+		}
+	}
+
+	if mode.IsDir() {
+		icon := DirectoryIcon(file.Name())
+		return blue + icon + " " + reset
+	}
+
+	if icon, found := SpecialFileIcon(file.Name()); found {
+		return icon
+	}
+
+	ext := filepath.Ext(file.Name())
+	icon, exists := FileIcons[ext]
+	if !exists && sniff && file.Type().IsRegular() {
+		if kind := ls.sniffKind(directory, file.Name()); kind.ext != "" {
+			ext = kind.ext
+			icon, exists = FileIcons[ext]
+		}
+	}
+	if exists {
+		if color := IconColor(ext, mode); color != "" {
+			return color + icon + reset
+		}
+		return icon
+	}
+
+	if mode&fs.ModePerm&0111 != 0 {
+		return green + " " + reset
+	}
+
+	return " " + reset
+}
+
+// IconColor returns the colour an icon from FileIcons is drawn in for a
+// file with extension ext, or "" when it keeps the terminal colour.
+// Scripts are green only when mode makes them executable.
+func IconColor(ext string, mode fs.FileMode) string {
+	switch ext {
+	case ".sh", ".ps1":
+		if mode&fs.ModePerm&0111 != 0 {
+			return brightGreen
+		} else {
+			return white
+		}
+	case ".cpp", ".hpp", ".cxx", ".hxx", ".dart", ".gd", ".v":
+		return blue
+	case ".css", ".ml", ".rst", ".nix":
+		return lightBlue
+	case ".c", ".h", ".mp3", ".m4a", ".ogg", ".flac", ".php", ".lua", ".sql", ".m":
+		return brightBlue
+	case ".png", ".jpg", ".jpeg", ".JPG", ".webp", ".R", ".ts", ".bmp":
+		return darkBlue
+	case ".md", ".epub", ".obj", ".go":
+		return cyan
+	case ".xml":
+		return lightCyan
+	case ".exe", ".desktop", ".mk":
+		return brightCyan
+	case ".gif", ".xcf", ".el", ".lisp":
+		return magenta
+	case ".cs", ".mp4", ".mkv", ".webm", ".org", ".ejs":
+		return darkMagenta
+	case ".js", ".lock":
+		return yellow
+	case ".json", ".tiff", ".nim":
+		return brightYellow
+	case ".patch", ".diff", ".py":
+		return darkYellow
+	case ".yml", ".yaml", ".pdf", ".db":
+		return brightRed
+	case ".deb":
+		return lightRed
+	case ".rb", ".cmake", ".pl", ".scala", ".erl", ".build":
+		return red
+	case ".htm", ".html", ".java", ".jar", ".git", ".ps", ".eps", ".swift":
+		return orange
+	case ".toml", ".zig":
+		return darkOrange
+	case ".tmux.conf":
+		return green
+	case ".xbps", ".vim", ".jai":
+		return darkGreen
+	case ".iso", ".asm", ".f90", ".groovy", ".ini", ".cfg":
+		return gray
+	case ".conf", ".bat", ".rs":
+		return darkGray
+	case ".fish", ".o", ".m4":
+		return lightGray
+	case ".1", ".hs":
+		return lightBrown
+	case ".txt", ".app":
+		return white
+	case ".zip", ".tar", ".gz", ".bz2", ".xz", ".7z", ".svg", ".kt", ".ex", ".zst":
+		return lightPurple
+	default:
+		return ""
+	}
+}
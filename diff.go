@@ -0,0 +1,248 @@
+package gols
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// diffNode is an entry of either or both directories being compared. a
+// or b is nil when the entry only exists on the other side.
+type diffNode struct {
+	name     string
+	a, b     *Entry
+	changes  []string
+	children []*diffNode
+	differs  bool
+}
+
+// diffCounts tallies the compared entries for the summary.
+type diffCounts struct {
+	onlyA, onlyB, changed, same int
+}
+
+// Diff prints the differences between dirA and dirB, as a tree with
+// opts.Recursive, like gols --diff. It reports whether the directories
+// are the same.
+func Diff(w io.Writer, dirA, dirB string, opts Options) (bool, error) {
+	opts.FS = nil
+	ls, err := newLister(opts)
+	if err != nil {
+		return false, err
+	}
+	return ls.runDiff(w, dirA, dirB)
+}
+
+// runDiff prints the entries that exist in only one of dirA and dirB or
+// differ in type, size, modification time, mode or content. With -r both
+// trees are compared and shown as a tree of the differences, after any
+// directories that could not be read. It reports whether the
+// directories are the same.
+func (ls *lister) runDiff(w io.Writer, dirA, dirB string) (bool, error) {
+	for _, dir := range []string{dirA, dirB} {
+		if err := ls.openArchive(dir); err != nil {
+			return false, err
+		}
+		info, err := ls.statPath(dir)
+		if err != nil {
+			return false, err
+		}
+		if !info.IsDir() {
+			return false, fmt.Errorf("%s is not a directory", dir)
+		}
+	}
+
+	// Both directories are walked like a tree listing of them, with the
+	// name filters left to the files, so that every directory is
+	// compared. Only directories on both sides are entered.
+	walk := ls.withoutIncludes()
+	if !ls.recursive {
+		walk.opts.MaxDepth = 0
+	}
+	var sides [2]map[string]Entry
+	children := map[string][]string{}
+	for side, dir := range []string{dirA, dirB} {
+		sides[side] = map[string]Entry{}
+		err := walk.walkEntries(context.Background(), dir, func(entry Entry) error {
+			if entry.Err != nil {
+				fmt.Fprintf(w, "%sError reading directory %s: %v%s\n", red, entry.Path, entry.Err, reset)
+				entry.Err = nil
+			}
+			if !entry.IsDir() && !ls.matcher.included(entry.Rel) {
+				return nil
+			}
+			parent := path.Dir(entry.Rel)
+			if _, found := sides[1-side][entry.Rel]; !found {
+				children[parent] = append(children[parent], entry.Name)
+			}
+			sides[side][entry.Rel] = entry
+			if side == 1 && entry.IsDir() {
+				if other, found := sides[0][entry.Rel]; !found || !other.IsDir() {
+					return fs.SkipDir
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+
+	var counts diffCounts
+	nodes := ls.buildDiff(sides, children, ".", &counts)
+
+	fmt.Fprintf(w, "%s-%s %s  %s+%s %s\n\n", red, reset, dirA, green, reset, dirB)
+	decorations := map[string]*diffNode{}
+	l := &Listing{Root: dirB, Dir: dirB, Tree: ls.recursive, Options: ls.opts, ls: ls}
+	l.Options.Summary = false
+	l.Entries = diffEntries(nodes, l.Entries, decorations)
+	l.decorate = func(entry Entry) (string, string) {
+		return decorations[entry.Rel].decoration()
+	}
+	if ls.recursive {
+		renderTree(w, l)
+	} else {
+		for _, entry := range l.Entries {
+			before, after := l.decorate(entry)
+			fmt.Fprint(w, before)
+			ls.printFile(w, entry, entry.Name, true)
+			fmt.Fprintln(w, after)
+		}
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Only in %s: %s%d%s\n", dirA, red, counts.onlyA, reset)
+	fmt.Fprintf(w, "Only in %s: %s%d%s\n", dirB, green, counts.onlyB, reset)
+	fmt.Fprintf(w, "Different: %s%d%s\n", yellow, counts.changed, reset)
+	fmt.Fprintf(w, "Identical: %s%d%s\n", gray, counts.same, reset)
+	return counts.onlyA+counts.onlyB+counts.changed == 0, nil
+}
+
+// buildDiff pairs up the entries of both sides below parent by name and
+// compares them, descending into directories present on both sides.
+func (ls *lister) buildDiff(sides [2]map[string]Entry, children map[string][]string, parent string, counts *diffCounts) []*diffNode {
+	names := children[parent]
+	sort.Strings(names)
+
+	nodes := make([]*diffNode, 0, len(names))
+	for _, name := range names {
+		rel := path.Join(parent, name)
+		node := &diffNode{name: name}
+		if entry, found := sides[0][rel]; found {
+			node.a = &entry
+		}
+		if entry, found := sides[1][rel]; found {
+			node.b = &entry
+		}
+		nodes = append(nodes, node)
+
+		switch {
+		case node.b == nil:
+			counts.onlyA++
+			node.differs = true
+			continue
+		case node.a == nil:
+			counts.onlyB++
+			node.differs = true
+			continue
+		}
+
+		node.changes = ls.compareEntries(node)
+		if len(node.changes) > 0 {
+			counts.changed++
+			node.differs = true
+		} else {
+			counts.same++
+		}
+
+		if node.a.IsDir() && node.b.IsDir() {
+			node.children = ls.buildDiff(sides, children, rel, counts)
+			for _, child := range node.children {
+				node.differs = node.differs || child.differs
+			}
+		}
+	}
+	return nodes
+}
+
+// diffEntries appends the entries of the nodes that differ, and of the
+// directories that lead to them, to entries in tree order, and records
+// which node each one shows.
+func diffEntries(nodes []*diffNode, entries []Entry, shown map[string]*diffNode) []Entry {
+	for _, node := range nodes {
+		if !node.differs {
+			continue
+		}
+		entry := node.b
+		if entry == nil {
+			entry = node.a
+		}
+		shown[entry.Rel] = node
+		entries = diffEntries(node.children, append(entries, *entry), shown)
+	}
+	return entries
+}
+
+// compareEntries describes how an entry present on both sides differs.
+// Contents are only hashed when the sizes are equal.
+func (ls *lister) compareEntries(node *diffNode) []string {
+	infoA, infoB := node.a.Info, node.b.Info
+	if infoA.Mode().Type() != infoB.Mode().Type() {
+		return []string{"type"}
+	}
+
+	var changes []string
+	if infoA.Mode().Perm() != infoB.Mode().Perm() {
+		changes = append(changes, fmt.Sprintf("mode %s → %s", infoA.Mode().Perm(), infoB.Mode().Perm()))
+	}
+	if infoA.IsDir() {
+		return changes
+	}
+
+	if infoA.Size() != infoB.Size() {
+		changes = append(changes, fmt.Sprintf("size %s → %s", formatSize(infoA.Size(), true), formatSize(infoB.Size(), true)))
+	} else if infoA.Mode().IsRegular() {
+		sumA, errA := ls.hashFile(node.a.Path, -1)
+		sumB, errB := ls.hashFile(node.b.Path, -1)
+		if errA == nil && errB == nil && sumA != sumB {
+			changes = append(changes, "content")
+		}
+	}
+
+	if infoA.Mode()&os.ModeSymlink != 0 {
+		if node.a.LinkTarget != node.b.LinkTarget {
+			changes = append(changes, fmt.Sprintf("target %s → %s", node.a.LinkTarget, node.b.LinkTarget))
+		}
+	}
+
+	timeA, timeB := infoA.ModTime().Truncate(time.Second), infoB.ModTime().Truncate(time.Second)
+	if !timeA.Equal(timeB) {
+		changes = append(changes, fmt.Sprintf("mtime %s → %s", timeA.Format("2006-01-02 15:04"), timeB.Format("2006-01-02 15:04")))
+	}
+	return changes
+}
+
+// decoration returns what is shown around the name of node: before it
+// - when it is only in the first directory, + only in the second and ~
+// when it differs on both sides, and after it what changed.
+func (node *diffNode) decoration() (string, string) {
+	marker := yellow + "~" + reset
+	switch {
+	case node.b == nil:
+		marker = red + "-" + reset
+	case node.a == nil:
+		marker = green + "+" + reset
+	case len(node.changes) == 0:
+		marker = " "
+	}
+	if len(node.changes) == 0 {
+		return marker + " ", ""
+	}
+	return marker + " ", "  " + yellow + strings.Join(node.changes, ", ") + reset
+}
@@ -0,0 +1,169 @@
+package gols
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeDiffSide creates files under dir with the same modification
+// time, so that only what the tests change differs.
+func writeDiffSide(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	writeFiles(t, dir, files)
+	mtime := time.Date(2024, 1, 31, 12, 0, 0, 0, time.Local)
+	for name := range files {
+		if err := os.Chtimes(filepath.Join(dir, filepath.FromSlash(name)), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := map[string]string{
+		"same.txt":     "same",
+		"content.txt":  "aaaa",
+		"size.txt":     "12345",
+		"onlya.txt":    "a",
+		"gone/x":       "x",
+		"sub/deep/f":   "one",
+		"sub/deep/g":   "g",
+		"lib.go":       "package lib\n",
+		"sub/other.go": "package sub\n",
+	}
+	b := map[string]string{
+		"same.txt":     "same",
+		"content.txt":  "bbbb",
+		"size.txt":     "123456",
+		"onlyb.txt":    "b",
+		"sub/deep/f":   "two",
+		"sub/deep/g":   "g",
+		"lib.go":       "package lib\n",
+		"sub/other.go": "package sub\n",
+	}
+
+	tests := []struct {
+		name string
+		opts func(*Options)
+		want []string
+		same bool
+	}{
+		{
+			name: "listing",
+			want: []string{
+				"~ content.txt  content",
+				"- gone",
+				"- onlya.txt",
+				"+ onlyb.txt",
+				"~ size.txt  size 5 B → 6 B",
+				"",
+				"Only in A: 2",
+				"Only in B: 1",
+				"Different: 2",
+				"Identical: 3",
+			},
+		},
+		{
+			name: "tree",
+			opts: func(o *Options) { o.Recursive = true },
+			want: []string{
+				"├── ~ content.txt  content",
+				"├── - gone",
+				"├── - onlya.txt",
+				"├── + onlyb.txt",
+				"├── ~ size.txt  size 5 B → 6 B",
+				"└──   sub",
+				"    └──   deep",
+				"        └── ~ f  content",
+				"",
+				"Only in A: 2",
+				"Only in B: 1",
+				"Different: 3",
+				"Identical: 6",
+			},
+		},
+		{
+			name: "tree of go files",
+			opts: func(o *Options) { o.Recursive, o.Extensions = true, []string{"go"} },
+			want: []string{
+				"└── - gone",
+				"",
+				"Only in A: 1",
+				"Only in B: 0",
+				"Different: 0",
+				"Identical: 4",
+			},
+		},
+	}
+	for _, test := range tests {
+		dirA, dirB := filepath.Join(t.TempDir(), "A"), filepath.Join(t.TempDir(), "B")
+		writeDiffSide(t, dirA, a)
+		writeDiffSide(t, dirB, b)
+		opts := DefaultOptions()
+		opts.NoIcons = true
+		if test.opts != nil {
+			test.opts(&opts)
+		}
+
+		var out bytes.Buffer
+		same, err := Diff(&out, dirA, dirB, opts)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		text := ansiEscapes.ReplaceAllString(out.String(), "")
+		text = strings.ReplaceAll(text, dirA, "A")
+		text = strings.ReplaceAll(text, dirB, "B")
+		got := strings.Split(strings.TrimSuffix(text, "\n"), "\n")[2:]
+		if same != test.same || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: Diff = %v\n%s\nwant\n%s", test.name, same, strings.Join(got, "\n"), strings.Join(test.want, "\n"))
+		}
+	}
+}
+
+func TestDiffSame(t *testing.T) {
+	files := map[string]string{"a": "a", "dir/b": "b"}
+	dirA, dirB := filepath.Join(t.TempDir(), "A"), filepath.Join(t.TempDir(), "B")
+	writeDiffSide(t, dirA, files)
+	writeDiffSide(t, dirB, files)
+
+	opts := DefaultOptions()
+	opts.Recursive = true
+	same, err := Diff(new(bytes.Buffer), dirA, dirB, opts)
+	if err != nil || !same {
+		t.Errorf("Diff of equal trees = %v, %v", same, err)
+	}
+
+	if err := os.Chmod(filepath.Join(dirB, "dir", "b"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if same, err := Diff(new(bytes.Buffer), dirA, dirB, opts); err != nil || same {
+		t.Errorf("Diff after a chmod = %v, %v", same, err)
+	}
+
+	if _, err := Diff(new(bytes.Buffer), dirA, filepath.Join(dirB, "a"), opts); err == nil {
+		t.Error("Diff with a file succeeded")
+	}
+}
+
+func TestDiffUnreadable(t *testing.T) {
+	files := map[string]string{"a": "a", "dir/b": "b"}
+	dirA, dirB := filepath.Join(t.TempDir(), "A"), filepath.Join(t.TempDir(), "B")
+	writeDiffSide(t, dirA, files)
+	writeDiffSide(t, dirB, files)
+	lockDir(t, filepath.Join(dirB, "dir"))
+
+	opts := DefaultOptions()
+	opts.Recursive = true
+	var out bytes.Buffer
+	if _, err := Diff(&out, dirA, dirB, opts); err != nil {
+		t.Fatal(err)
+	}
+	got := ansiEscapes.ReplaceAllString(out.String(), "")
+	if want := "Error reading directory " + filepath.Join(dirB, "dir"); !strings.HasPrefix(got, want) {
+		t.Errorf("Diff wrote %q, want it to start with %q", got, want)
+	}
+}
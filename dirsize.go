@@ -0,0 +1,54 @@
+package gols
+
+import "context"
+
+// dirSize returns the total size of the files below directory, at any
+// depth, hidden and filtered ones included, as du does. Directories that
+// cannot be read count as empty. The sizes of the directories below are
+// worked out on the way and kept for when they are listed too.
+func (ls *lister) dirSize(directory string) int64 {
+	if size, found := ls.cache.dirSizes[directory]; found {
+		return size
+	}
+
+	// stack holds the directories the walk is in, directory first, each
+	// with the size counted in it so far.
+	type dirTotal struct {
+		path string
+		size int64
+	}
+	stack := []dirTotal{{path: directory}}
+	pop := func() {
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		ls.cache.dirSizes[top.path] = top.size
+		if len(stack) > 0 {
+			stack[len(stack)-1].size += top.size
+		}
+	}
+
+	ls.unfiltered().walkEntries(context.Background(), directory, func(entry Entry) error {
+		for len(stack) > entry.Depth+1 {
+			pop()
+		}
+		switch {
+		case entry.entered:
+			stack = append(stack, dirTotal{path: entry.Path})
+		case !entry.IsDir():
+			stack[len(stack)-1].size += entry.Info.Size()
+		}
+		return nil
+	})
+	for len(stack) > 0 {
+		pop()
+	}
+	return ls.cache.dirSizes[directory]
+}
+
+// entrySize returns the size the listing shows for entry.
+func (ls *lister) entrySize(entry Entry) int64 {
+	if ls.opts.DirSizes && entry.IsDir() {
+		return ls.dirSize(entry.Path)
+	}
+	return entry.Info.Size()
+}
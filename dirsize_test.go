@@ -0,0 +1,45 @@
+package gols
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDirSize(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a":            {Data: make([]byte, 100)},
+		"dir/b.go":     {Data: make([]byte, 200)},
+		"dir/.hidden":  {Data: make([]byte, 30)},
+		"dir/sub/c":    {Data: make([]byte, 4)},
+		"dir/sub/deep": {Data: make([]byte, 1)},
+		"dir/.git/x":   {Data: make([]byte, 1000)},
+		"empty":        {Mode: fs.ModeDir | 0o755},
+	}
+	opts := DefaultOptions()
+	opts.FS = fsys
+	opts.Extensions = []string{"go"}
+	opts.MaxDepth = 0
+	ls, err := newLister(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		dir  string
+		size int64
+	}{
+		{"dir/sub", 5},
+		{"dir", 1235},
+		{".", 1335},
+		{"empty", 0},
+	}
+	for _, test := range tests {
+		if got := ls.dirSize(test.dir); got != test.size {
+			t.Errorf("dirSize(%s) = %d, want %d", test.dir, got, test.size)
+		}
+	}
+	if size, found := ls.cache.dirSizes["dir/.git"]; !found || size != 1000 {
+		t.Errorf("the size of dir/.git was not kept: %d, %v", size, found)
+	}
+}
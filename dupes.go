@@ -0,0 +1,180 @@
+package gols
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// dupesPartialSize is how much of each file is hashed to split groups of
+// equal size before any file is read in full.
+const dupesPartialSize = 4096
+
+// dupeFile is a regular file found by the --dupes walk.
+type dupeFile struct {
+	entry     os.DirEntry
+	directory string
+	path      string
+	size      int64
+}
+
+// Dupes prints the groups of files with identical contents under root,
+// like gols --dupes. It reports whether any duplicates were found.
+func Dupes(w io.Writer, root string, opts Options) (bool, error) {
+	opts.FS = nil
+	ls, err := newLister(opts)
+	if err != nil {
+		return false, err
+	}
+	return ls.runDupes(w, root)
+}
+
+// runDupes walks the tree under root like the tree view does, with the same
+// filters and limits, and prints every group of files with identical
+// contents. Files are grouped by size, then by a hash of their first
+// bytes and finally by a hash of their whole contents, so most files are
+// never read. Hard links to the same inode are the same file, not
+// duplicates. Directories that cannot be read are reported before the
+// groups. It reports whether any duplicates were found.
+func (ls *lister) runDupes(w io.Writer, root string) (bool, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return false, err
+	}
+	if !info.IsDir() {
+		return false, fmt.Errorf("%s is not a directory", root)
+	}
+
+	bySize := map[int64][]dupeFile{}
+	inodes := map[[2]uint64]bool{}
+	err = ls.walkEntries(context.Background(), root, func(entry Entry) error {
+		if entry.Err != nil {
+			fmt.Fprintf(w, "%sError reading directory %s: %v%s\n", red, entry.Path, entry.Err, reset)
+		}
+		if !entry.Info.Mode().IsRegular() || entry.Info.Size() == 0 {
+			return nil
+		}
+		if id, ok := fileID(entry.Info); ok {
+			if inodes[id] {
+				return nil
+			}
+			inodes[id] = true
+		}
+		bySize[entry.Info.Size()] = append(bySize[entry.Info.Size()], dupeFile{
+			entry:     entry.file,
+			directory: entry.Dir,
+			path:      entry.Path,
+			size:      entry.Info.Size(),
+		})
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	var groups [][]dupeFile
+	for size, files := range bySize {
+		if len(files) < 2 {
+			continue
+		}
+		for _, partial := range ls.groupByHash(files, dupesPartialSize) {
+			if size <= dupesPartialSize {
+				groups = append(groups, partial)
+				continue
+			}
+			groups = append(groups, ls.groupByHash(partial, -1)...)
+		}
+	}
+
+	if len(groups) == 0 {
+		return false, nil
+	}
+
+	// Groups that waste the most space come first.
+	sort.Slice(groups, func(i, j int) bool {
+		wastedI := groups[i][0].size * int64(len(groups[i])-1)
+		wastedJ := groups[j][0].size * int64(len(groups[j])-1)
+		if wastedI != wastedJ {
+			return wastedI > wastedJ
+		}
+		return groups[i][0].path < groups[j][0].path
+	})
+
+	var wasted int64
+	var duplicates int
+	for _, group := range groups {
+		sort.Slice(group, func(i, j int) bool { return group[i].path < group[j].path })
+
+		size := group[0].size
+		fmt.Fprintf(w, "%s%s%s × %d, %s%s%s wasted\n", yellow, formatSize(size, true), reset,
+			len(group), red, formatSize(size*int64(len(group)-1), true), reset)
+		for _, file := range group {
+			rel, err := filepath.Rel(root, file.path)
+			if err != nil {
+				rel = file.path
+			}
+			info, _ := file.entry.Info()
+			fmt.Fprintln(w, "    "+ls.getFileIcon(file.entry, info.Mode(), file.directory)+rel)
+		}
+		fmt.Fprintln(w)
+
+		wasted += size * int64(len(group)-1)
+		duplicates += len(group) - 1
+	}
+
+	fmt.Fprintf(w, "Groups: %s%d%s\n", blue, len(groups), reset)
+	fmt.Fprintf(w, "Duplicate files: %s%d%s\n", yellow, duplicates, reset)
+	fmt.Fprintf(w, "Wasted space: %s%s%s\n", red, formatSize(wasted, true), reset)
+	return true, nil
+}
+
+// groupByHash splits files into groups with the same SHA-256 of their
+// first limit bytes, or of their whole contents when limit is negative,
+// and drops the groups with a single file. Unreadable files are left out.
+func (ls *lister) groupByHash(files []dupeFile, limit int64) [][]dupeFile {
+	byHash := map[[sha256.Size]byte][]dupeFile{}
+	var order [][sha256.Size]byte
+	for _, file := range files {
+		sum, err := ls.hashFile(file.path, limit)
+		if err != nil {
+			continue
+		}
+		if _, found := byHash[sum]; !found {
+			order = append(order, sum)
+		}
+		byHash[sum] = append(byHash[sum], file)
+	}
+
+	var groups [][]dupeFile
+	for _, sum := range order {
+		if len(byHash[sum]) > 1 {
+			groups = append(groups, byHash[sum])
+		}
+	}
+	return groups
+}
+
+func (ls *lister) hashFile(name string, limit int64) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+
+	f, err := ls.sysOpen(name)
+	if err != nil {
+		return sum, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if limit >= 0 {
+		r = io.LimitReader(f, limit)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return sum, err
+	}
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
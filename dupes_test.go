@@ -0,0 +1,131 @@
+package gols
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDupes(t *testing.T) {
+	big := strings.Repeat("x", dupesPartialSize+10)
+	tests := []struct {
+		name  string
+		files map[string]string
+		links map[string]string // hard links to create, new name to old
+		opts  func(*Options)
+		want  []string
+	}{
+		{
+			name:  "none",
+			files: map[string]string{"a": "one", "b": "two", "c": "three"},
+		},
+		{
+			name:  "one group",
+			files: map[string]string{"a": "same", "b": "same", "sub/c": "same", "d": "diff"},
+			want:  []string{"4 B × 3, 8 B wasted", "    a", "    b", "    sub/c"},
+		},
+		{
+			name:  "same start, different end",
+			files: map[string]string{"a": big + "1", "b": big + "2"},
+		},
+		{
+			name:  "large files in full",
+			files: map[string]string{"a": big, "b": big},
+			want:  []string{"4.01 KB × 2, 4.01 KB wasted", "    a", "    b"},
+		},
+		{
+			name:  "most wasted first",
+			files: map[string]string{"a": "xy", "b": "xy", "c": "abcdef", "d": "abcdef"},
+			want:  []string{"6 B × 2, 6 B wasted", "    c", "    d", "2 B × 2, 2 B wasted", "    a", "    b"},
+		},
+		{
+			name:  "empty files are not duplicates",
+			files: map[string]string{"a": "", "b": ""},
+		},
+		{
+			name:  "hard links are the same file",
+			files: map[string]string{"a": "same"},
+			links: map[string]string{"b": "a"},
+		},
+		{
+			name:  "hidden files left out",
+			files: map[string]string{"a": "same", ".b": "same"},
+		},
+		{
+			name:  "hidden files",
+			files: map[string]string{"a": "same", ".b": "same"},
+			opts:  func(o *Options) { o.All = true },
+			want:  []string{"4 B × 2, 4 B wasted", "    .b", "    a"},
+		},
+		{
+			name:  "filtered",
+			files: map[string]string{"a.go": "same", "b.txt": "same", "c.go": "same"},
+			opts:  func(o *Options) { o.Extensions = []string{"go"} },
+			want:  []string{"4 B × 2, 4 B wasted", "    a.go", "    c.go"},
+		},
+	}
+	for _, test := range tests {
+		root := t.TempDir()
+		writeFiles(t, root, test.files)
+		for name, old := range test.links {
+			if err := os.Link(filepath.Join(root, old), filepath.Join(root, name)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		opts := DefaultOptions()
+		opts.NoIcons = true
+		if test.opts != nil {
+			test.opts(&opts)
+		}
+
+		var out bytes.Buffer
+		found, err := Dupes(&out, root, opts)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		got := dupeGroupLines(out.String())
+		if found != (len(test.want) > 0) || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: Dupes = %v\n%s\nwant\n%s", test.name, found, strings.Join(got, "\n"), strings.Join(test.want, "\n"))
+		}
+	}
+
+	if _, err := Dupes(new(bytes.Buffer), filepath.Join(t.TempDir(), "missing"), DefaultOptions()); err == nil {
+		t.Error("Dupes of a missing directory succeeded")
+	}
+}
+
+// dupeGroupLines returns the lines of the groups Dupes printed, without
+// colours and the totals.
+func dupeGroupLines(out string) []string {
+	var lines []string
+	for _, line := range strings.Split(ansiEscapes.ReplaceAllString(out, ""), "\n") {
+		if strings.HasPrefix(line, "Groups:") {
+			break
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func TestDupesUnreadable(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"a": "same", "b": "same", "locked/c": "same"})
+	lockDir(t, filepath.Join(root, "locked"))
+
+	opts := DefaultOptions()
+	opts.NoIcons = true
+	var out bytes.Buffer
+	found, err := Dupes(&out, root, opts)
+	if err != nil || !found {
+		t.Fatalf("Dupes = %v, %v", found, err)
+	}
+	got := ansiEscapes.ReplaceAllString(out.String(), "")
+	if !strings.HasPrefix(got, "Error reading directory "+filepath.Join(root, "locked")) || !strings.Contains(got, "4 B × 2") {
+		t.Errorf("Dupes wrote %q, want the error reading locked and then a and b", got)
+	}
+}
@@ -0,0 +1,109 @@
+package gols
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync/atomic"
+)
+
+// Find prints the entries under root whose names contain pattern, or
+// match it as a glob, like gols --find. It reports whether anything
+// matched.
+func Find(w io.Writer, root, pattern string, opts Options) (bool, error) {
+	opts.FS = nil
+	ls, err := newLister(opts)
+	if err != nil {
+		return false, err
+	}
+	return ls.runFind(w, root, pattern)
+}
+
+// runFind walks root with a goroutine per directory and prints the path
+// of every entry whose name matches pattern, relative to root, as soon
+// as it is found. The order of the results is therefore not stable.
+// Directories that cannot be read are reported among them. It reports
+// whether anything matched.
+func (ls *lister) runFind(w io.Writer, root, pattern string) (bool, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return false, err
+	}
+	if !info.IsDir() {
+		return false, fmt.Errorf("%s is not a directory", root)
+	}
+
+	// Only this goroutine writes to w; the walkers send it their lines.
+	var found atomic.Bool
+	lines := make(chan string, 256)
+	go func() {
+		err = ls.withoutIncludes().walkParallel(context.Background(), root, func(entry Entry) error {
+			if entry.Err != nil {
+				lines <- fmt.Sprintf("%sError reading directory %s: %v%s", red, entry.Path, entry.Err, reset)
+			}
+			if ls.findMatches(entry.file, entry.Dir, entry.Rel, pattern) {
+				found.Store(true)
+				lines <- ls.formatFindResult(entry.file, entry.Dir, entry.Rel)
+			}
+			return nil
+		})
+		close(lines)
+	}()
+
+	for line := range lines {
+		fmt.Fprintln(w, line)
+	}
+	return found.Load(), err
+}
+
+// findMatches applies the pattern and the listing filters to an entry.
+func (ls *lister) findMatches(file os.DirEntry, dir, relPath, pattern string) bool {
+	if !ls.findNameMatches(file.Name(), pattern) || !ls.matcher.included(relPath) {
+		return false
+	}
+
+	switch {
+	case ls.opts.HiddenOnly && !strings.HasPrefix(file.Name(), "."):
+		return false
+	case ls.opts.DirsOnly && !file.IsDir():
+		return false
+	case ls.opts.FilesOnly && file.IsDir():
+		return false
+	case ls.opts.SymlinksOnly && file.Type()&os.ModeSymlink == 0:
+		return false
+	}
+	return ls.matchesPredicates(file, dir)
+}
+
+// findNameMatches matches a name against pattern: as a shell pattern
+// when it contains wildcards, otherwise as a substring.
+func (ls *lister) findNameMatches(name, pattern string) bool {
+	pattern, name = ls.matcher.fold(pattern), ls.matcher.fold(name)
+	if strings.ContainsAny(pattern, "*?[") {
+		ok, _ := path.Match(pattern, name)
+		return ok
+	}
+	return strings.Contains(name, pattern)
+}
+
+// formatFindResult renders a match as its icon followed by its relative
+// path, with the parent directories in blue.
+func (ls *lister) formatFindResult(file os.DirEntry, dir, relPath string) string {
+	info, err := file.Info()
+	if err != nil {
+		return relPath
+	}
+
+	parent := ""
+	if relDir := path.Dir(relPath); relDir != "." {
+		parent = blue + relDir + "/" + reset
+	}
+
+	if file.IsDir() {
+		return ls.getFileIcon(file, info.Mode(), dir) + parent + blue + file.Name() + reset
+	}
+	return ls.getFileIcon(file, info.Mode(), dir) + parent + file.Name()
+}
@@ -0,0 +1,98 @@
+package gols
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"main.go":               "package main\n",
+		"README.md":             "# x\n",
+		"src/lib.go":            "package lib\n",
+		"src/lib_test.go":       "package lib\n",
+		"src/.cache/lib.go":     "package cache\n",
+		"src/vendor/dep/dep.go": "package dep\n",
+		"docs/library.md":       "# library\n",
+	})
+	if err := os.Symlink("lib.go", filepath.Join(root, "src", "link.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		pattern string
+		opts    func(*Options)
+		want    string
+	}{
+		{name: "substring", pattern: "lib", want: "docs/library.md src/lib.go src/lib_test.go"},
+		{name: "glob", pattern: "*.go", want: "main.go src/lib.go src/lib_test.go src/link.go src/vendor/dep/dep.go"},
+		{name: "case", pattern: "readme", want: ""},
+		{name: "ignoring case", pattern: "readme", opts: func(o *Options) { o.IgnoreCase = true }, want: "README.md"},
+		{name: "hidden", pattern: "lib.go", opts: func(o *Options) { o.All = true }, want: "src/.cache/lib.go src/lib.go"},
+		{name: "depth", pattern: "*.go", opts: func(o *Options) { o.MaxDepth = 0 }, want: "main.go"},
+		{name: "directories", pattern: "*", opts: func(o *Options) { o.DirsOnly = true }, want: "docs src src/vendor src/vendor/dep"},
+		{name: "symlinks", pattern: "*", opts: func(o *Options) { o.SymlinksOnly = true }, want: "src/link.go"},
+		{name: "ignored", pattern: "*.go", opts: func(o *Options) { o.Ignore = []string{"vendor", "*_test.go"} }, want: "main.go src/lib.go src/link.go"},
+		{name: "path glob", pattern: "*", opts: func(o *Options) { o.Match = []string{"src/*.go"} }, want: "src/lib.go src/lib_test.go src/link.go"},
+		{name: "nothing", pattern: "zzz", want: ""},
+	}
+	for _, test := range tests {
+		opts := DefaultOptions()
+		opts.NoIcons = true
+		if test.opts != nil {
+			test.opts(&opts)
+		}
+		var out bytes.Buffer
+		found, err := Find(&out, root, test.pattern, opts)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		// Results come in the order they are found.
+		lines := strings.Fields(ansiEscapes.ReplaceAllString(out.String(), ""))
+		sort.Strings(lines)
+		if got := strings.Join(lines, " "); got != test.want || found != (test.want != "") {
+			t.Errorf("%s: Find = %v, %q, want %q", test.name, found, got, test.want)
+		}
+	}
+
+	if _, err := Find(new(bytes.Buffer), filepath.Join(root, "main.go"), "x", DefaultOptions()); err == nil {
+		t.Error("Find in a file succeeded")
+	}
+}
+
+// lockDir takes away the permissions of dir, so that reading it fails,
+// until the test ends.
+func lockDir(t *testing.T, dir string) {
+	t.Helper()
+	if os.Getuid() == 0 {
+		t.Skip("root can read any directory")
+	}
+	if err := os.Chmod(dir, 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(dir, 0o755) })
+}
+
+func TestFindUnreadable(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"a.go": "", "locked/b.go": ""})
+	lockDir(t, filepath.Join(root, "locked"))
+
+	opts := DefaultOptions()
+	opts.NoIcons = true
+	var out bytes.Buffer
+	found, err := Find(&out, root, "*.go", opts)
+	if err != nil || !found {
+		t.Fatalf("Find = %v, %v", found, err)
+	}
+	got := ansiEscapes.ReplaceAllString(out.String(), "")
+	if !strings.Contains(got, "a.go\n") || !strings.Contains(got, "Error reading directory "+filepath.Join(root, "locked")) {
+		t.Errorf("Find wrote %q, want a.go and the error reading locked", got)
+	}
+}
@@ -0,0 +1,66 @@
+package gols
+
+import (
+	"io/fs"
+	"slices"
+	"syscall"
+)
+
+// fileID returns the device and inode of info, when its file system has
+// them.
+func fileID(info fs.FileInfo) ([2]uint64, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return [2]uint64{}, false
+	}
+	return [2]uint64{uint64(stat.Dev), uint64(stat.Ino)}, true
+}
+
+// rootAncestors starts the list of directories a walk is in with root.
+func (ls *lister) rootAncestors(root string) [][2]uint64 {
+	info, err := ls.statPath(root)
+	if err != nil {
+		return nil
+	}
+	if id, ok := fileID(info); ok {
+		return [][2]uint64{id}
+	}
+	return nil
+}
+
+// descendInto decides whether the tree walk enters entry, given the
+// directories it is in, the root first. A directory, or with --follow a
+// symlink to one, is entered unless --one-file-system keeps it out, or
+// it is one of those directories already, which is reported as a loop.
+// It returns the ancestors of what is below entry.
+func (ls *lister) descendInto(entry Entry, ancestors [][2]uint64) (descend, loop bool, below [][2]uint64) {
+	info := entry.Info
+	if info.Mode()&fs.ModeSymlink != 0 {
+		if !ls.opts.Follow {
+			return false, false, nil
+		}
+		target, err := ls.statPath(entry.Path)
+		if err != nil || !target.IsDir() {
+			return false, false, nil
+		}
+		info = target
+	} else if !info.IsDir() {
+		return false, false, nil
+	}
+
+	id, ok := fileID(info)
+	if !ok {
+		// Without inodes a loop would go unnoticed, so only real
+		// directories are entered.
+		return entry.IsDir(), false, ancestors
+	}
+	if ls.opts.OneFileSystem && len(ancestors) > 0 && id[0] != ancestors[0][0] {
+		return false, false, nil
+	}
+	if slices.Contains(ancestors, id) {
+		return false, true, nil
+	}
+	// Siblings share ancestors, and --find walks them in parallel, so
+	// each gets a slice of its own.
+	return true, false, append(slices.Clip(ancestors), id)
+}
@@ -0,0 +1,120 @@
+package gols
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestFollow(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"real/file":       "x",
+		"real/inner/deep": "y",
+	})
+	for link, target := range map[string]string{
+		"real/up":         "..",
+		"real/inner/loop": "../inner",
+		"tolink":          "real",
+		"tofile":          "real/file",
+		"broken":          "missing",
+	} {
+		if err := os.Symlink(target, filepath.Join(root, filepath.FromSlash(link))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		follow bool
+		want   map[string]string // rel to what the walk made of it
+	}{
+		{
+			name: "not followed",
+			want: map[string]string{
+				"broken": "", "real": "entered", "real/file": "", "real/inner": "entered",
+				"real/inner/deep": "", "real/inner/loop": "", "real/up": "",
+				"tofile": "", "tolink": "",
+			},
+		},
+		{
+			name:   "followed",
+			follow: true,
+			want: map[string]string{
+				"broken": "", "real": "entered", "real/file": "", "real/inner": "entered",
+				"real/inner/deep": "", "real/inner/loop": "loop", "real/up": "loop",
+				"tofile": "", "tolink": "entered", "tolink/file": "", "tolink/inner": "entered",
+				"tolink/inner/deep": "", "tolink/inner/loop": "loop", "tolink/up": "loop",
+			},
+		},
+	}
+	for _, test := range tests {
+		opts := DefaultOptions()
+		opts.Follow = test.follow
+		got := map[string]string{}
+		err := Walk(context.Background(), root, opts, func(e Entry) error {
+			switch {
+			case e.loop:
+				got[e.Rel] = "loop"
+			case e.entered:
+				got[e.Rel] = "entered"
+			default:
+				got[e.Rel] = ""
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		for rel, want := range test.want {
+			if what, found := got[rel]; !found || what != want {
+				t.Errorf("%s: %s is %q (found %v), want %q", test.name, rel, what, found, want)
+			}
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("%s: walked %v", test.name, got)
+		}
+	}
+}
+
+// TestFollowParallel walks a tree with sibling directories four levels
+// deep, each with a link back to itself, in parallel. Run with -race.
+func TestFollowParallel(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"a/b/c/d/x": "x",
+		"a/b/k/l/x": "x",
+		"a/b/m/x":   "x",
+		"a/n/x":     "x",
+	})
+	for link, target := range map[string]string{
+		"a/b/c/d/top":  "../../../..",
+		"a/b/c/d/back": "..",
+		"a/b/k/l/back": "..",
+		"a/b/m/self":   ".",
+		"a/n/up":       "..",
+	} {
+		if err := os.Symlink(target, filepath.Join(root, filepath.FromSlash(link))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := DefaultOptions()
+	opts.Follow = true
+	opts.NoIcons = true
+	for i := 0; i < 20; i++ {
+		var out bytes.Buffer
+		if _, err := Find(&out, root, "x", opts); err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Fields(ansiEscapes.ReplaceAllString(out.String(), ""))
+		sort.Strings(lines)
+		if got, want := strings.Join(lines, " "), "a/b/c/d/x a/b/k/l/x a/b/m/x a/n/x"; got != want {
+			t.Fatalf("Find = %s, want %s", got, want)
+		}
+	}
+}
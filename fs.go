@@ -0,0 +1,89 @@
+package gols
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ReadLinkFS is implemented by file systems that have symbolic links,
+// so that listings can show what they point to. Without it symlink
+// targets are unknown.
+type ReadLinkFS interface {
+	fs.FS
+	ReadLink(name string) (string, error)
+}
+
+// LstatFS is implemented by file systems that can describe a symbolic
+// link itself rather than what it points to. Without it the entries of
+// a directory still tell symlinks apart, but a listed path is always
+// followed.
+type LstatFS interface {
+	fs.FS
+	Lstat(name string) (fs.FileInfo, error)
+}
+
+// fsName turns a path built with filepath into a name for an fs.FS.
+func fsName(name string) string {
+	return filepath.ToSlash(filepath.Clean(name))
+}
+
+func (ls *lister) sysStat(name string) (fs.FileInfo, error) {
+	if ls.fsys != nil {
+		return fs.Stat(ls.fsys, fsName(name))
+	}
+	return os.Stat(name)
+}
+
+func (ls *lister) sysLstat(name string) (fs.FileInfo, error) {
+	if ls.fsys != nil {
+		if lfs, ok := ls.fsys.(LstatFS); ok {
+			return lfs.Lstat(fsName(name))
+		}
+		return fs.Stat(ls.fsys, fsName(name))
+	}
+	return os.Lstat(name)
+}
+
+func (ls *lister) sysReadDir(name string) ([]fs.DirEntry, error) {
+	if ls.fsys != nil {
+		return fs.ReadDir(ls.fsys, fsName(name))
+	}
+	return os.ReadDir(name)
+}
+
+func (ls *lister) sysReadLink(name string) (string, error) {
+	if ls.fsys != nil {
+		if rfs, ok := ls.fsys.(ReadLinkFS); ok {
+			return rfs.ReadLink(fsName(name))
+		}
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: errors.ErrUnsupported}
+	}
+	return os.Readlink(name)
+}
+
+func (ls *lister) sysOpen(name string) (fs.File, error) {
+	if ls.fsys != nil {
+		return ls.fsys.Open(fsName(name))
+	}
+	return os.Open(name)
+}
+
+// dirIsEmpty reports whether the directory name has no entries, reading
+// no more of it than needed.
+func (ls *lister) dirIsEmpty(name string) bool {
+	f, err := ls.sysOpen(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	dir, ok := f.(fs.ReadDirFile)
+	if !ok {
+		return false
+	}
+	_, err = dir.ReadDir(1)
+	return err == io.EOF
+}
@@ -0,0 +1,281 @@
+package gols
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// gitignoreRule is one line of a .gitignore, info/exclude or global
+// excludes file. base is the slash-separated directory, relative to the
+// repository root, that the rule was read from.
+type gitignoreRule struct {
+	base     string
+	segments []string
+	anchored bool
+	dirOnly  bool
+	negate   bool
+}
+
+// gitignoreMatcher answers ignore queries for one repository, loading
+// the .gitignore of each directory the first time it is needed.
+type gitignoreMatcher struct {
+	root     string
+	global   []gitignoreRule
+	dirRules map[string][]gitignoreRule
+	verdicts map[string]bool
+}
+
+func (ls *lister) filterGitignored(files []os.DirEntry, directory string) []os.DirEntry {
+	var result []os.DirEntry
+	for _, file := range files {
+		if !ls.gitIgnored(directory, file.Name(), file.IsDir()) {
+			result = append(result, file)
+		}
+	}
+	return result
+}
+
+// gitIgnored reports whether name in directory is ignored by git.
+func (ls *lister) gitIgnored(directory, name string, isDir bool) bool {
+	if name == ".git" {
+		return true
+	}
+
+	dir, err := filepath.Abs(directory)
+	if err != nil {
+		return false
+	}
+	ls.cache.gitignoreMu.Lock()
+	defer ls.cache.gitignoreMu.Unlock()
+	m := ls.gitignoreFor(dir)
+
+	rel, err := filepath.Rel(m.root, filepath.Join(dir, name))
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	return m.ignored(filepath.ToSlash(rel), isDir)
+}
+
+// gitignoreFor returns the matcher of the repository containing dir. The
+// repository root is the nearest parent with a .git entry; outside a
+// repository, dir itself is used so its .gitignore files still apply.
+func (ls *lister) gitignoreFor(dir string) *gitignoreMatcher {
+	root, found := ls.cache.gitignoreRoots[dir]
+	if !found {
+		root = findRepoRoot(dir)
+		if root == "" {
+			root = dir
+		}
+		ls.cache.gitignoreRoots[dir] = root
+	}
+
+	if m, found := ls.cache.gitignoreMatchers[root]; found {
+		return m
+	}
+
+	m := &gitignoreMatcher{
+		root:     root,
+		dirRules: map[string][]gitignoreRule{},
+		verdicts: map[string]bool{},
+	}
+	if excludes := globalExcludesFile(root); excludes != "" {
+		m.global = append(m.global, readGitignore(excludes, "")...)
+	}
+	m.global = append(m.global, readGitignore(filepath.Join(root, ".git", "info", "exclude"), "")...)
+	ls.cache.gitignoreMatchers[root] = m
+	return m
+}
+
+func findRepoRoot(dir string) string {
+	for {
+		if _, err := os.Lstat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// ignored reports whether rel, a slash-separated path relative to the
+// repository root, is ignored. As in git, nothing inside an ignored
+// directory can be re-included, and among the rules that match, the last
+// one wins: global excludes, then info/exclude, then .gitignore files
+// from the root down.
+func (m *gitignoreMatcher) ignored(rel string, isDir bool) bool {
+	key := rel
+	if isDir {
+		key += "/"
+	}
+	if verdict, found := m.verdicts[key]; found {
+		return verdict
+	}
+
+	verdict := false
+	parent := path.Dir(rel)
+	if parent != "." && m.ignored(parent, true) {
+		verdict = true
+	} else {
+		rules := append([]gitignoreRule(nil), m.global...)
+		dirs := []string{""}
+		if parent != "." {
+			parts := strings.Split(parent, "/")
+			for i := range parts {
+				dirs = append(dirs, strings.Join(parts[:i+1], "/"))
+			}
+		}
+		for _, dir := range dirs {
+			rules = append(rules, m.rulesFor(dir)...)
+		}
+
+		for i := len(rules) - 1; i >= 0; i-- {
+			if rules[i].matches(rel, isDir) {
+				verdict = !rules[i].negate
+				break
+			}
+		}
+	}
+
+	m.verdicts[key] = verdict
+	return verdict
+}
+
+func (m *gitignoreMatcher) rulesFor(dir string) []gitignoreRule {
+	if rules, found := m.dirRules[dir]; found {
+		return rules
+	}
+	rules := readGitignore(filepath.Join(m.root, filepath.FromSlash(dir), ".gitignore"), dir)
+	m.dirRules[dir] = rules
+	return rules
+}
+
+func (r gitignoreRule) matches(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+
+	if r.base != "" {
+		if !strings.HasPrefix(rel, r.base+"/") {
+			return false
+		}
+		rel = rel[len(r.base)+1:]
+	}
+
+	if !r.anchored {
+		ok, _ := path.Match(r.segments[0], path.Base(rel))
+		return ok
+	}
+	return matchSegments(r.segments, strings.Split(rel, "/"))
+}
+
+// readGitignore parses a gitignore-format file. Missing files simply
+// contribute no rules.
+func readGitignore(name, base string) []gitignoreRule {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var rules []gitignoreRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasSuffix(line, "\\ ") {
+			line = strings.TrimRight(line, " \t\r")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := gitignoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		// A slash at the start or in the middle anchors the pattern to
+		// the directory of the .gitignore; otherwise it matches at any
+		// depth by name.
+		rule.anchored = strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		line = strings.ReplaceAll(line, "[!", "[^")
+		rule.segments = strings.Split(line, "/")
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// globalExcludesFile returns core.excludesFile from the repository and
+// user git configuration, falling back to git's default location.
+func globalExcludesFile(root string) string {
+	home, _ := os.UserHomeDir()
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" && home != "" {
+		configHome = filepath.Join(home, ".config")
+	}
+
+	var excludes string
+	for _, config := range []string{
+		filepath.Join(configHome, "git", "config"),
+		filepath.Join(home, ".gitconfig"),
+		filepath.Join(root, ".git", "config"),
+	} {
+		if value := readGitConfigValue(config, "core", "excludesfile"); value != "" {
+			excludes = value
+		}
+	}
+
+	if excludes == "" {
+		if configHome == "" {
+			return ""
+		}
+		return filepath.Join(configHome, "git", "ignore")
+	}
+	if strings.HasPrefix(excludes, "~/") {
+		excludes = filepath.Join(home, excludes[2:])
+	}
+	return excludes
+}
+
+// readGitConfigValue reads a single key from an ini-style git config
+// file. It understands just enough of the format for core settings.
+func readGitConfigValue(name, section, key string) string {
+	f, err := os.Open(name)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	var current, value string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			current = strings.ToLower(strings.Trim(line, "[] \t"))
+			continue
+		}
+		k, v, found := strings.Cut(line, "=")
+		if found && current == section && strings.EqualFold(strings.TrimSpace(k), key) {
+			value = strings.Trim(strings.TrimSpace(v), `"`)
+		}
+	}
+	return value
+}
@@ -0,0 +1,132 @@
+package gols
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeFiles creates files under dir, with their directories.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadGitignore(t *testing.T) {
+	name := filepath.Join(t.TempDir(), ".gitignore")
+	writeFiles(t, filepath.Dir(name), map[string]string{".gitignore": "" +
+		"# comment\n" +
+		"\n" +
+		"*.o\n" +
+		"build/\n" +
+		"/root.txt\n" +
+		"docs/*.html\n" +
+		"!keep.o\n" +
+		"\\!bang\n" +
+		"\\#hash\n" +
+		"trailing   \n" +
+		"space\\ \n" +
+		"[!a]*.tmp\n" +
+		"/\n",
+	})
+
+	want := []gitignoreRule{
+		{base: "sub", segments: []string{"*.o"}},
+		{base: "sub", segments: []string{"build"}, dirOnly: true},
+		{base: "sub", segments: []string{"root.txt"}, anchored: true},
+		{base: "sub", segments: []string{"docs", "*.html"}, anchored: true},
+		{base: "sub", segments: []string{"keep.o"}, negate: true},
+		{base: "sub", segments: []string{"!bang"}},
+		{base: "sub", segments: []string{"#hash"}},
+		{base: "sub", segments: []string{"trailing"}},
+		{base: "sub", segments: []string{"space\\ "}},
+		{base: "sub", segments: []string{"[^a]*.tmp"}},
+	}
+	if got := readGitignore(name, "sub"); !reflect.DeepEqual(got, want) {
+		t.Errorf("readGitignore =\n%+v\nwant\n%+v", got, want)
+	}
+	if got := readGitignore(filepath.Join(t.TempDir(), "missing"), ""); got != nil {
+		t.Errorf("readGitignore of a missing file = %+v", got)
+	}
+}
+
+func TestGitIgnored(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	writeFiles(t, home, map[string]string{".config/git/ignore": "*.swp\n"})
+
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".git/info/exclude": "secret.txt\n",
+		".gitignore":        "*.o\n!keep.o\nbuild/\n/top.txt\ndocs/**/*.html\nlogs\n",
+		"src/.gitignore":    "gen.go\n/local\n!top.txt\n",
+		"logs/.gitignore":   "!important\n",
+	})
+
+	tests := []struct {
+		rel   string
+		isDir bool
+		want  bool
+	}{
+		{".git", true, true},
+		{"main.go", false, false},
+		{"main.o", false, true},
+		{"src/lib.o", false, true},
+		{"keep.o", false, false},
+		{"build", true, true},
+		{"build", false, false},
+		{"src/build", true, true},
+		{"build/out.txt", false, true},
+		{"top.txt", false, true},
+		{"src/top.txt", false, false},
+		{"docs/index.html", false, true},
+		{"docs/a/b/page.html", false, true},
+		{"src/index.html", false, false},
+		{"src/gen.go", false, true},
+		{"src/pkg/gen.go", false, true},
+		{"gen.go", false, false},
+		{"src/local", false, true},
+		{"src/pkg/local", false, false},
+		{"logs/important", false, true},
+		{"secret.txt", false, true},
+		{"src/.main.go.swp", false, true},
+	}
+	ls := defaultLister()
+	for _, test := range tests {
+		dir, name := filepath.Split(filepath.Join(root, filepath.FromSlash(test.rel)))
+		if got := ls.gitIgnored(dir, name, test.isDir); got != test.want {
+			t.Errorf("gitIgnored(%s, dir %v) = %v, want %v", test.rel, test.isDir, got, test.want)
+		}
+	}
+}
+
+func TestGlobalExcludesFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	root := t.TempDir()
+
+	if got, want := globalExcludesFile(root), filepath.Join(home, ".config", "git", "ignore"); got != want {
+		t.Errorf("default globalExcludesFile = %s, want %s", got, want)
+	}
+
+	writeFiles(t, home, map[string]string{".gitconfig": "[user]\n\tname = x\n[core]\n\texcludesFile = ~/.excludes\n"})
+	if got, want := globalExcludesFile(root), filepath.Join(home, ".excludes"); got != want {
+		t.Errorf("globalExcludesFile from ~/.gitconfig = %s, want %s", got, want)
+	}
+
+	writeFiles(t, root, map[string]string{".git/config": "; local\n[CORE]\n\texcludesfile = \"/etc/excludes\"\n"})
+	if got, want := globalExcludesFile(root), "/etc/excludes"; got != want {
+		t.Errorf("globalExcludesFile from .git/config = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,246 @@
+package gols
+
+import (
+	"container/heap"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gitCommitInfo is what the --git-log column shows about a commit.
+type gitCommitInfo struct {
+	hash   string
+	author string
+	date   time.Time
+}
+
+// gitCommit is a parsed commit object.
+type gitCommit struct {
+	gitCommitInfo
+	tree    string
+	parents []string
+}
+
+// gitHistory maps every path of a repository's HEAD tree, directories
+// included, to the last commit that changed it. It is computed once per
+// repository, the first time any of its entries is listed.
+type gitHistory struct {
+	lastCommit map[string]*gitCommitInfo
+}
+
+// gitLastCommit returns the last commit touching name in directory, or
+// nil when it is untracked or outside a repository.
+func (ls *lister) gitLastCommit(directory, name string) *gitCommitInfo {
+	dir, err := filepath.Abs(directory)
+	if err != nil {
+		return nil
+	}
+	root := findRepoRoot(dir)
+	if root == "" {
+		return nil
+	}
+	rel, err := filepath.Rel(root, filepath.Join(dir, name))
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return nil
+	}
+
+	history, found := ls.cache.gitHistories[root]
+	if !found {
+		history = loadGitHistory(root)
+		ls.cache.gitHistories[root] = history
+	}
+	return history.lastCommit[filepath.ToSlash(rel)]
+}
+
+// formatGitLog renders the column for a commit, padding the author to
+// authorWidth so the names that follow line up.
+func formatGitLog(commit *gitCommitInfo, authorWidth int) string {
+	if commit == nil {
+		return gray + padRight("-", 10) + " " + padRight("-", 7) + " " + padRight("-", authorWidth) + reset
+	}
+	return yellow + commit.date.Format("2006-01-02") + reset + " " +
+		brightPurple + commit.hash[:7] + reset + " " +
+		cyan + padRight(commit.author, authorWidth) + reset
+}
+
+// loadGitHistory walks the history from HEAD, newest commit first, and
+// attributes each changed path to the first commit seen changing it. The
+// walk stops as soon as every path in HEAD has been attributed.
+func loadGitHistory(root string) *gitHistory {
+	history := &gitHistory{lastCommit: map[string]*gitCommitInfo{}}
+
+	gitDir := findGitDir(root)
+	if gitDir == "" {
+		return history
+	}
+	_, head := readHead(gitDir)
+	if head == "" {
+		return history
+	}
+
+	store := newGitObjectStore(gitDir)
+	headCommit, err := store.readCommit(head)
+	if err != nil {
+		return history
+	}
+
+	pending := map[string]bool{}
+	files := map[string]string{}
+	if err := store.flattenTree(headCommit.tree, "", files); err != nil {
+		return history
+	}
+	for file := range files {
+		for p := file; p != "."; p = path.Dir(p) {
+			pending[p] = true
+		}
+	}
+
+	queue := &gitCommitQueue{headCommit}
+	seen := map[string]bool{head: true}
+	for queue.Len() > 0 && len(pending) > 0 {
+		commit := heap.Pop(queue).(*gitCommit)
+
+		var parents []*gitCommit
+		for _, hash := range commit.parents {
+			parent, err := store.readCommit(hash)
+			if err != nil {
+				continue
+			}
+			parents = append(parents, parent)
+			if !seen[hash] {
+				seen[hash] = true
+				heap.Push(queue, parent)
+			}
+		}
+
+		// A path changed in a merge only counts when it differs from
+		// every parent, as git log's history simplification does.
+		changed := map[string]int{}
+		if len(parents) == 0 {
+			store.diffTrees("", commit.tree, "", func(p string) { changed[p]++ })
+		}
+		for _, parent := range parents {
+			store.diffTrees(parent.tree, commit.tree, "", func(p string) { changed[p]++ })
+		}
+
+		for file, count := range changed {
+			if count < max(len(parents), 1) {
+				continue
+			}
+			for p := file; p != "."; p = path.Dir(p) {
+				if pending[p] {
+					history.lastCommit[p] = &commit.gitCommitInfo
+					delete(pending, p)
+				}
+			}
+		}
+	}
+	return history
+}
+
+func (s *gitObjectStore) readCommit(hash string) (*gitCommit, error) {
+	typ, data, err := s.read(hash)
+	if err != nil {
+		return nil, err
+	}
+	if typ != "commit" {
+		return nil, fmt.Errorf("%s is a %s, not a commit", hash, typ)
+	}
+
+	commit := &gitCommit{gitCommitInfo: gitCommitInfo{hash: hash}}
+	header, _, _ := strings.Cut(string(data), "\n\n")
+	for _, line := range strings.Split(header, "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "tree":
+			commit.tree = value
+		case "parent":
+			commit.parents = append(commit.parents, value)
+		case "author":
+			commit.author, commit.date = parseGitSignature(value)
+		}
+	}
+	return commit, nil
+}
+
+// parseGitSignature splits "Name <email> 1700000000 +0100" into the name
+// and the time in its original zone.
+func parseGitSignature(value string) (string, time.Time) {
+	name, rest, found := strings.Cut(value, " <")
+	if !found {
+		return value, time.Time{}
+	}
+	_, stamp, _ := strings.Cut(rest, "> ")
+	seconds, zone, _ := strings.Cut(stamp, " ")
+
+	unix, _ := strconv.ParseInt(seconds, 10, 64)
+	t := time.Unix(unix, 0)
+	if offset, err := strconv.Atoi(zone); err == nil {
+		t = t.In(time.FixedZone(zone, (offset/100*60+offset%100)*60))
+	}
+	return name, t
+}
+
+// diffTrees calls changed with the path of every blob that differs
+// between trees a and b, skipping subtrees with equal hashes. An empty
+// hash stands for an empty tree.
+func (s *gitObjectStore) diffTrees(a, b, prefix string, changed func(string)) {
+	if a == b {
+		return
+	}
+	entriesA, entriesB := s.treeEntries(a), s.treeEntries(b)
+
+	for name, entryB := range entriesB {
+		entryA, found := entriesA[name]
+		switch {
+		case found && entryA.hash == entryB.hash && entryA.mode == entryB.mode:
+		case entryB.isTree():
+			base := ""
+			if found && entryA.isTree() {
+				base = entryA.hash
+			}
+			s.diffTrees(base, entryB.hash, prefix+name+"/", changed)
+		default:
+			changed(prefix + name)
+		}
+	}
+	for name, entryA := range entriesA {
+		if _, found := entriesB[name]; !found {
+			if entryA.isTree() {
+				s.diffTrees(entryA.hash, "", prefix+name+"/", changed)
+			} else {
+				changed(prefix + name)
+			}
+		}
+	}
+}
+
+func (s *gitObjectStore) treeEntries(tree string) map[string]gitTreeEntry {
+	entries := map[string]gitTreeEntry{}
+	if tree == "" {
+		return entries
+	}
+	list, _ := s.readTree(tree)
+	for _, entry := range list {
+		entries[entry.name] = entry
+	}
+	return entries
+}
+
+// gitCommitQueue orders commits newest first by author date.
+type gitCommitQueue []*gitCommit
+
+func (q gitCommitQueue) Len() int           { return len(q) }
+func (q gitCommitQueue) Less(i, j int) bool { return q[i].date.After(q[j].date) }
+func (q gitCommitQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *gitCommitQueue) Push(x any)        { *q = append(*q, x.(*gitCommit)) }
+
+func (q *gitCommitQueue) Pop() any {
+	old := *q
+	commit := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return commit
+}
@@ -0,0 +1,96 @@
+package gols
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseGitSignature(t *testing.T) {
+	tests := []struct {
+		value  string
+		name   string
+		unix   int64
+		offset int
+	}{
+		{"Ada Lovelace <ada@example.com> 1700000000 +0100", "Ada Lovelace", 1700000000, 3600},
+		{"gols <gols@example.com> 1700000000 -0530", "gols", 1700000000, -(5*3600 + 30*60)},
+		{"gols <> 0 +0000", "gols", 0, 0},
+		{"no email", "no email", -62135596800, 0},
+	}
+	for _, test := range tests {
+		name, when := parseGitSignature(test.value)
+		_, offset := when.Zone()
+		if name != test.name || when.Unix() != test.unix || offset != test.offset {
+			t.Errorf("parseGitSignature(%q) = %q, %v (offset %d), want %q, %d, %d",
+				test.value, name, when, offset, test.name, test.unix, test.offset)
+		}
+	}
+}
+
+func TestFormatGitLog(t *testing.T) {
+	commit := &gitCommitInfo{
+		hash:   "0123456789abcdef0123456789abcdef01234567",
+		author: "gols",
+		date:   time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC),
+	}
+	tests := []struct {
+		commit *gitCommitInfo
+		want   string
+	}{
+		{commit, "2024-01-31 0123456 gols  "},
+		{nil, "-          -       -     "},
+	}
+	for _, test := range tests {
+		if got := ansiEscapes.ReplaceAllString(formatGitLog(test.commit, 6), ""); got != test.want {
+			t.Errorf("formatGitLog(%v) = %q, want %q", test.commit, got, test.want)
+		}
+	}
+}
+
+func TestGitLastCommit(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q", "-b", "main")
+
+	commit := func(author, date string, files map[string]string) {
+		t.Helper()
+		writeFiles(t, dir, files)
+		gitCommand(t, dir, "add", "-A")
+		gitCommand(t, dir, "commit", "-q", "-m", "change", "--author", author+" <"+strings.ToLower(author)+"@example.com>", "--date", date)
+	}
+	commit("Alice", "2024-01-01T00:00:00+0000", map[string]string{"a.txt": "a", "sub/b.txt": "b", "sub/c.txt": "c"})
+	commit("Bob", "2024-01-02T00:00:00+0000", map[string]string{"sub/b.txt": "b2"})
+	gitCommand(t, dir, "checkout", "-q", "-b", "feature")
+	commit("Dave", "2024-01-03T00:00:00+0000", map[string]string{"sub/c.txt": "c2"})
+	gitCommand(t, dir, "checkout", "-q", "main")
+	commit("Carol", "2024-01-04T00:00:00+0000", map[string]string{"a.txt": "a2"})
+	gitCommand(t, dir, "merge", "-q", "--no-ff", "-m", "merge", "feature")
+	if err := os.WriteFile(filepath.Join(dir, "untracked"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"a.txt":     "Carol",
+		"sub":       "Dave",
+		"sub/b.txt": "Bob",
+		"sub/c.txt": "Dave",
+		"untracked": "",
+	}
+	ls := defaultLister()
+	for rel, want := range tests {
+		dirname, name := filepath.Split(filepath.Join(dir, filepath.FromSlash(rel)))
+		got := ""
+		if commit := ls.gitLastCommit(dirname, name); commit != nil {
+			got = commit.author
+		}
+		if got != want {
+			t.Errorf("gitLastCommit(%s) by %q, want %q", rel, got, want)
+		}
+	}
+
+	if commit := ls.gitLastCommit(t.TempDir(), "x"); commit != nil {
+		t.Errorf("gitLastCommit outside a repository = %+v", commit)
+	}
+}
@@ -0,0 +1,451 @@
+package gols
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// gitObjectStore reads objects from a repository's .git directory, both
+// loose and from pack files, so gols never needs a git binary.
+type gitObjectStore struct {
+	gitDir string
+	packs  []*gitPack
+	loaded bool
+}
+
+// gitPack is a pack file together with its version 2 index.
+type gitPack struct {
+	path    string
+	fanout  [256]uint32
+	hashes  []byte
+	offsets []uint32
+	large   []byte
+}
+
+var errGitObjectNotFound = errors.New("git object not found")
+
+// findGitDir returns the git directory of the repository rooted at root,
+// following the "gitdir:" file used by worktrees and submodules.
+func findGitDir(root string) string {
+	dotGit := filepath.Join(root, ".git")
+	info, err := os.Stat(dotGit)
+	if err != nil {
+		return ""
+	}
+	if info.IsDir() {
+		return dotGit
+	}
+
+	data, err := os.ReadFile(dotGit)
+	if err != nil {
+		return ""
+	}
+	dir, found := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !found {
+		return ""
+	}
+	dir = strings.TrimSpace(dir)
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(root, dir)
+	}
+	return dir
+}
+
+// commonGitDir returns the directory holding objects and refs, which for
+// linked worktrees differs from the per-worktree git directory.
+func commonGitDir(gitDir string) string {
+	data, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return gitDir
+	}
+	dir := strings.TrimSpace(string(data))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(gitDir, dir)
+	}
+	return dir
+}
+
+// readHead returns the branch HEAD points to, or an empty branch and the
+// commit hash when HEAD is detached.
+func readHead(gitDir string) (branch, hash string) {
+	data, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return "", ""
+	}
+	head := strings.TrimSpace(string(data))
+	if ref, found := strings.CutPrefix(head, "ref: "); found {
+		return strings.TrimPrefix(ref, "refs/heads/"), resolveRef(gitDir, ref)
+	}
+	return "", head
+}
+
+// resolveRef looks a ref up as a loose file, then in packed-refs.
+func resolveRef(gitDir, ref string) string {
+	for _, dir := range []string{gitDir, commonGitDir(gitDir)} {
+		if data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(ref))); err == nil {
+			value := strings.TrimSpace(string(data))
+			if target, found := strings.CutPrefix(value, "ref: "); found {
+				return resolveRef(gitDir, target)
+			}
+			return value
+		}
+	}
+
+	f, err := os.Open(filepath.Join(commonGitDir(gitDir), "packed-refs"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		hash, name, found := strings.Cut(scanner.Text(), " ")
+		if found && name == ref {
+			return hash
+		}
+	}
+	return ""
+}
+
+func newGitObjectStore(gitDir string) *gitObjectStore {
+	return &gitObjectStore{gitDir: commonGitDir(gitDir)}
+}
+
+// read returns the type and content of the object with the given hex hash.
+func (s *gitObjectStore) read(hash string) (string, []byte, error) {
+	raw, err := hex.DecodeString(hash)
+	if err != nil || len(raw) != 20 {
+		return "", nil, fmt.Errorf("invalid object name %q", hash)
+	}
+
+	if typ, data, err := s.readLoose(hash); err == nil {
+		return typ, data, nil
+	}
+
+	s.loadPacks()
+	for _, pack := range s.packs {
+		if offset, found := pack.find(raw); found {
+			return s.readPacked(pack, offset)
+		}
+	}
+	return "", nil, errGitObjectNotFound
+}
+
+func (s *gitObjectStore) readLoose(hash string) (string, []byte, error) {
+	f, err := os.Open(filepath.Join(s.gitDir, "objects", hash[:2], hash[2:]))
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+
+	zr, err := zlib.NewReader(f)
+	if err != nil {
+		return "", nil, err
+	}
+	defer zr.Close()
+
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return "", nil, err
+	}
+	header, content, found := bytes.Cut(data, []byte{0})
+	if !found {
+		return "", nil, fmt.Errorf("corrupt object %s", hash)
+	}
+	typ, _, _ := strings.Cut(string(header), " ")
+	return typ, content, nil
+}
+
+func (s *gitObjectStore) loadPacks() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+
+	indexes, _ := filepath.Glob(filepath.Join(s.gitDir, "objects", "pack", "*.idx"))
+	for _, index := range indexes {
+		if pack, err := loadGitPack(index); err == nil {
+			s.packs = append(s.packs, pack)
+		}
+	}
+}
+
+func loadGitPack(index string) (*gitPack, error) {
+	data, err := os.ReadFile(index)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 8+256*4 || !bytes.Equal(data[:4], []byte{0xff, 't', 'O', 'c'}) || binary.BigEndian.Uint32(data[4:8]) != 2 {
+		return nil, fmt.Errorf("unsupported pack index %s", index)
+	}
+
+	pack := &gitPack{path: strings.TrimSuffix(index, ".idx") + ".pack"}
+	for i := range pack.fanout {
+		pack.fanout[i] = binary.BigEndian.Uint32(data[8+i*4:])
+	}
+
+	n := int(pack.fanout[255])
+	pos := 8 + 256*4
+	if len(data) < pos+n*(20+4+4) {
+		return nil, fmt.Errorf("truncated pack index %s", index)
+	}
+	pack.hashes = data[pos : pos+n*20]
+	pos += n*20 + n*4
+	pack.offsets = make([]uint32, n)
+	for i := range pack.offsets {
+		pack.offsets[i] = binary.BigEndian.Uint32(data[pos+i*4:])
+	}
+	pack.large = data[pos+n*4:]
+	return pack, nil
+}
+
+func (p *gitPack) find(hash []byte) (int64, bool) {
+	lo := uint32(0)
+	if hash[0] > 0 {
+		lo = p.fanout[hash[0]-1]
+	}
+	hi := p.fanout[hash[0]]
+
+	i := lo + uint32(sort.Search(int(hi-lo), func(i int) bool {
+		start := int(lo+uint32(i)) * 20
+		return bytes.Compare(p.hashes[start:start+20], hash) >= 0
+	}))
+	if i >= hi || !bytes.Equal(p.hashes[i*20:i*20+20], hash) {
+		return 0, false
+	}
+
+	offset := p.offsets[i]
+	if offset&0x80000000 == 0 {
+		return int64(offset), true
+	}
+	large := int(offset&0x7fffffff) * 8
+	if large+8 > len(p.large) {
+		return 0, false
+	}
+	return int64(binary.BigEndian.Uint64(p.large[large:])), true
+}
+
+var gitObjectTypes = map[byte]string{1: "commit", 2: "tree", 3: "blob", 4: "tag"}
+
+func (s *gitObjectStore) readPacked(pack *gitPack, offset int64) (string, []byte, error) {
+	f, err := os.Open(pack.path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+	return s.readPackedAt(f, pack, offset, 0)
+}
+
+func (s *gitObjectStore) readPackedAt(f *os.File, pack *gitPack, offset int64, depth int) (string, []byte, error) {
+	if depth > 64 {
+		return "", nil, fmt.Errorf("delta chain too long in %s", pack.path)
+	}
+
+	r := bufio.NewReader(io.NewSectionReader(f, offset, 1<<62))
+	b, err := r.ReadByte()
+	if err != nil {
+		return "", nil, err
+	}
+	kind := (b >> 4) & 7
+	for b&0x80 != 0 {
+		if b, err = r.ReadByte(); err != nil {
+			return "", nil, err
+		}
+	}
+
+	var baseType string
+	var base []byte
+	switch kind {
+	case 6:
+		distance, err := readOffsetVarint(r)
+		if err != nil {
+			return "", nil, err
+		}
+		baseType, base, err = s.readPackedAt(f, pack, offset-distance, depth+1)
+		if err != nil {
+			return "", nil, err
+		}
+	case 7:
+		var ref [20]byte
+		if _, err := io.ReadFull(r, ref[:]); err != nil {
+			return "", nil, err
+		}
+		baseType, base, err = s.read(hex.EncodeToString(ref[:]))
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	zr, err := zlib.NewReader(r)
+	if err != nil {
+		return "", nil, err
+	}
+	defer zr.Close()
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if kind == 6 || kind == 7 {
+		data, err = applyGitDelta(base, data)
+		return baseType, data, err
+	}
+	typ, found := gitObjectTypes[kind]
+	if !found {
+		return "", nil, fmt.Errorf("unknown object type %d in %s", kind, pack.path)
+	}
+	return typ, data, nil
+}
+
+// readOffsetVarint decodes the big-endian, off-by-one variable length
+// integer used by OFS_DELTA entries and version 4 index paths.
+func readOffsetVarint(r io.ByteReader) (int64, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	value := int64(b & 0x7f)
+	for b&0x80 != 0 {
+		if b, err = r.ReadByte(); err != nil {
+			return 0, err
+		}
+		value = (value+1)<<7 | int64(b&0x7f)
+	}
+	return value, nil
+}
+
+func applyGitDelta(base, delta []byte) ([]byte, error) {
+	readSize := func() int {
+		size, shift := 0, 0
+		for len(delta) > 0 {
+			b := delta[0]
+			delta = delta[1:]
+			size |= int(b&0x7f) << shift
+			shift += 7
+			if b&0x80 == 0 {
+				break
+			}
+		}
+		return size
+	}
+
+	if readSize() != len(base) {
+		return nil, errors.New("delta base size mismatch")
+	}
+	out := make([]byte, 0, readSize())
+
+	for len(delta) > 0 {
+		op := delta[0]
+		delta = delta[1:]
+
+		if op&0x80 == 0 {
+			if op == 0 || int(op) > len(delta) {
+				return nil, errors.New("corrupt delta")
+			}
+			out = append(out, delta[:op]...)
+			delta = delta[op:]
+			continue
+		}
+
+		var offset, size int
+		for i := 0; i < 7; i++ {
+			if op&(1<<i) == 0 {
+				continue
+			}
+			if len(delta) == 0 {
+				return nil, errors.New("corrupt delta")
+			}
+			if i < 4 {
+				offset |= int(delta[0]) << (8 * i)
+			} else {
+				size |= int(delta[0]) << (8 * (i - 4))
+			}
+			delta = delta[1:]
+		}
+		if size == 0 {
+			size = 0x10000
+		}
+		if offset+size > len(base) {
+			return nil, errors.New("corrupt delta")
+		}
+		out = append(out, base[offset:offset+size]...)
+	}
+	return out, nil
+}
+
+// commitTree returns the tree hash of a commit.
+func (s *gitObjectStore) commitTree(commit string) (string, error) {
+	typ, data, err := s.read(commit)
+	if err != nil {
+		return "", err
+	}
+	if typ != "commit" {
+		return "", fmt.Errorf("%s is a %s, not a commit", commit, typ)
+	}
+	tree, found := strings.CutPrefix(string(data), "tree ")
+	if !found || len(tree) < 40 {
+		return "", fmt.Errorf("corrupt commit %s", commit)
+	}
+	return tree[:40], nil
+}
+
+// gitTreeEntry is one line of a tree object.
+type gitTreeEntry struct {
+	mode string
+	name string
+	hash string
+}
+
+func (e gitTreeEntry) isTree() bool { return e.mode == "40000" }
+
+func (s *gitObjectStore) readTree(tree string) ([]gitTreeEntry, error) {
+	typ, data, err := s.read(tree)
+	if err != nil {
+		return nil, err
+	}
+	if typ != "tree" {
+		return nil, fmt.Errorf("%s is a %s, not a tree", tree, typ)
+	}
+
+	var entries []gitTreeEntry
+	for len(data) > 0 {
+		header, rest, found := bytes.Cut(data, []byte{0})
+		if !found || len(rest) < 20 {
+			return nil, fmt.Errorf("corrupt tree %s", tree)
+		}
+		mode, name, _ := strings.Cut(string(header), " ")
+		entries = append(entries, gitTreeEntry{mode: mode, name: name, hash: hex.EncodeToString(rest[:20])})
+		data = rest[20:]
+	}
+	return entries, nil
+}
+
+// flattenTree records every blob and submodule commit below tree in
+// files, keyed by its slash-separated path, with its hex hash.
+func (s *gitObjectStore) flattenTree(tree, prefix string, files map[string]string) error {
+	entries, err := s.readTree(tree)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !entry.isTree() {
+			files[prefix+entry.name] = entry.hash
+			continue
+		}
+		if err := s.flattenTree(entry.hash, prefix+entry.name+"/", files); err != nil {
+			return err
+		}
+	}
+	return nil
+}
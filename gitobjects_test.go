@@ -0,0 +1,360 @@
+package gols
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// gitObjectHash returns the object name git gives content of type typ.
+func gitObjectHash(typ string, content []byte) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "%s %d\x00", typ, len(content))
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func zlibBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// writeLooseObject stores content as a loose object of type typ in gitDir
+// and returns its name.
+func writeLooseObject(t *testing.T, gitDir, typ string, content []byte) string {
+	t.Helper()
+	hash := gitObjectHash(typ, content)
+	dir := filepath.Join(gitDir, "objects", hash[:2])
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	data := append([]byte(fmt.Sprintf("%s %d\x00", typ, len(content))), content...)
+	if err := os.WriteFile(filepath.Join(dir, hash[2:]), zlibBytes(t, data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+// gitTree encodes the entries of a tree object, which must be given in
+// git's order.
+func gitTree(entries ...gitTreeEntry) []byte {
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		raw, _ := hex.DecodeString(entry.hash)
+		fmt.Fprintf(&buf, "%s %s\x00", entry.mode, entry.name)
+		buf.Write(raw)
+	}
+	return buf.Bytes()
+}
+
+// deltaSize encodes a size in the little-endian varint of delta headers.
+func deltaSize(n int) []byte {
+	var out []byte
+	for {
+		b := byte(n & 0x7f)
+		n >>= 7
+		if n == 0 {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}
+
+// deltaCopy encodes a copy instruction of size bytes at offset.
+func deltaCopy(offset, size int) []byte {
+	op := byte(0x80)
+	var args []byte
+	for i := 0; i < 4; i++ {
+		if b := byte(offset >> (8 * i)); b != 0 {
+			op |= 1 << i
+			args = append(args, b)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if b := byte(size >> (8 * i)); b != 0 {
+			op |= 1 << (4 + i)
+			args = append(args, b)
+		}
+	}
+	return append([]byte{op}, args...)
+}
+
+func concat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func TestReadOffsetVarint(t *testing.T) {
+	tests := []struct {
+		in      []byte
+		want    int64
+		wantErr bool
+	}{
+		{in: []byte{0x00}, want: 0},
+		{in: []byte{0x7f}, want: 127},
+		{in: []byte{0x80, 0x00}, want: 128},
+		{in: []byte{0x81, 0x00}, want: 256},
+		{in: []byte{0xff, 0x7f}, want: 16511},
+		{in: []byte{0x80, 0x80, 0x00}, want: 16512},
+		{in: []byte{0x80}, wantErr: true},
+		{in: nil, wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := readOffsetVarint(bytes.NewReader(test.in))
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Errorf("readOffsetVarint(% x) = %d, %v, want %d (error %v)", test.in, got, err, test.want, test.wantErr)
+		}
+	}
+}
+
+func TestApplyGitDelta(t *testing.T) {
+	base := []byte("hello, world\n")
+	tests := []struct {
+		name    string
+		delta   []byte
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "copy",
+			delta: concat(deltaSize(13), deltaSize(5), deltaCopy(7, 5)),
+			want:  "world",
+		},
+		{
+			name:  "insert",
+			delta: concat(deltaSize(13), deltaSize(3), []byte{3, 'a', 'b', 'c'}),
+			want:  "abc",
+		},
+		{
+			name:  "copy and insert",
+			delta: concat(deltaSize(13), deltaSize(15), deltaCopy(0, 7), []byte{3, 'g', 'o', ' '}, deltaCopy(7, 6)),
+			want:  "hello, go world\n",
+		},
+		{
+			name:    "wrong base size",
+			delta:   concat(deltaSize(12), deltaSize(5), deltaCopy(7, 5)),
+			wantErr: true,
+		},
+		{
+			name:    "copy past the base",
+			delta:   concat(deltaSize(13), deltaSize(5), deltaCopy(10, 5)),
+			wantErr: true,
+		},
+		{
+			name:    "truncated insert",
+			delta:   concat(deltaSize(13), deltaSize(3), []byte{3, 'a'}),
+			wantErr: true,
+		},
+		{
+			name:    "reserved opcode",
+			delta:   concat(deltaSize(13), deltaSize(0), []byte{0}),
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		got, err := applyGitDelta(base, test.delta)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%s: applyGitDelta = %q, want an error", test.name, got)
+			}
+			continue
+		}
+		if err != nil || string(got) != test.want {
+			t.Errorf("%s: applyGitDelta = %q, %v, want %q", test.name, got, err, test.want)
+		}
+	}
+}
+
+func TestLooseObjects(t *testing.T) {
+	gitDir := t.TempDir()
+	blob := writeLooseObject(t, gitDir, "blob", []byte("package gols\n"))
+	other := writeLooseObject(t, gitDir, "blob", []byte("# gols\n"))
+	sub := writeLooseObject(t, gitDir, "tree", gitTree(
+		gitTreeEntry{mode: "100644", name: "main.go", hash: blob},
+	))
+	root := writeLooseObject(t, gitDir, "tree", gitTree(
+		gitTreeEntry{mode: "100644", name: "README.md", hash: other},
+		gitTreeEntry{mode: "40000", name: "cmd", hash: sub},
+		gitTreeEntry{mode: "160000", name: "vendor", hash: blob},
+	))
+	commit := writeLooseObject(t, gitDir, "commit", []byte("tree "+root+"\nauthor A <a@example.com> 1700000000 +0000\n\nfirst\n"))
+
+	store := newGitObjectStore(gitDir)
+	typ, data, err := store.read(blob)
+	if err != nil || typ != "blob" || string(data) != "package gols\n" {
+		t.Fatalf("read(%s) = %q, %q, %v", blob, typ, data, err)
+	}
+
+	tree, err := store.commitTree(commit)
+	if err != nil || tree != root {
+		t.Fatalf("commitTree = %s, %v, want %s", tree, err, root)
+	}
+	if _, err := store.commitTree(blob); err == nil {
+		t.Errorf("commitTree of a blob succeeded")
+	}
+
+	files := map[string]string{}
+	if err := store.flattenTree(tree, "", files); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"README.md": other, "cmd/main.go": blob, "vendor": blob}
+	if len(files) != len(want) {
+		t.Errorf("flattenTree = %v, want %v", files, want)
+	}
+	for name, hash := range want {
+		if files[name] != hash {
+			t.Errorf("flattenTree[%s] = %s, want %s", name, files[name], hash)
+		}
+	}
+
+	if _, _, err := store.read(gitObjectHash("blob", []byte("missing"))); err != errGitObjectNotFound {
+		t.Errorf("read of a missing object: %v, want %v", err, errGitObjectNotFound)
+	}
+	if _, _, err := store.read("not a hash"); err == nil {
+		t.Errorf("read of an invalid name succeeded")
+	}
+}
+
+// packObject is one object written by writeGitPack.
+type packObject struct {
+	kind    byte
+	data    []byte // the content, or the delta for kind 6
+	base    int    // for kind 6, the index of the base object
+	content []byte // what the object reads back as, to name it
+	typ     string
+}
+
+// writeGitPack writes a pack and its version 2 index to gitDir.
+func writeGitPack(t *testing.T, gitDir string, objects []packObject) {
+	t.Helper()
+	var pack bytes.Buffer
+	pack.WriteString("PACK")
+	binary.Write(&pack, binary.BigEndian, uint32(2))
+	binary.Write(&pack, binary.BigEndian, uint32(len(objects)))
+
+	type named struct {
+		hash   []byte
+		offset uint32
+	}
+	var names []named
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = pack.Len()
+
+		size := len(object.data)
+		b := object.kind<<4 | byte(size&0x0f)
+		size >>= 4
+		for size > 0 {
+			pack.WriteByte(b | 0x80)
+			b = byte(size & 0x7f)
+			size >>= 7
+		}
+		pack.WriteByte(b)
+
+		if object.kind == 6 {
+			distance := offsets[i] - offsets[object.base]
+			encoded := []byte{byte(distance & 0x7f)}
+			for distance >>= 7; distance > 0; distance >>= 7 {
+				distance--
+				encoded = append([]byte{byte(0x80 | distance&0x7f)}, encoded...)
+			}
+			pack.Write(encoded)
+		}
+		pack.Write(zlibBytes(t, object.data))
+
+		raw, _ := hex.DecodeString(gitObjectHash(object.typ, object.content))
+		names = append(names, named{raw, uint32(offsets[i])})
+	}
+	sort.Slice(names, func(i, j int) bool { return bytes.Compare(names[i].hash, names[j].hash) < 0 })
+
+	var index bytes.Buffer
+	index.Write([]byte{0xff, 't', 'O', 'c'})
+	binary.Write(&index, binary.BigEndian, uint32(2))
+	for i := 0; i < 256; i++ {
+		count := 0
+		for _, name := range names {
+			if int(name.hash[0]) <= i {
+				count++
+			}
+		}
+		binary.Write(&index, binary.BigEndian, uint32(count))
+	}
+	for _, name := range names {
+		index.Write(name.hash)
+	}
+	index.Write(make([]byte, 4*len(names)))
+	for _, name := range names {
+		binary.Write(&index, binary.BigEndian, name.offset)
+	}
+
+	dir := filepath.Join(gitDir, "objects", "pack")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "pack-test.pack"), pack.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "pack-test.idx"), index.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPackedObjects(t *testing.T) {
+	base := []byte("the quick brown fox jumps over the lazy dog\n")
+	changed := []byte("the quick brown cat jumps over the lazy dog\n")
+	delta := concat(deltaSize(len(base)), deltaSize(len(changed)), deltaCopy(0, 16), []byte{3, 'c', 'a', 't'}, deltaCopy(19, len(base)-19))
+	// Larger than 15 bytes, so the object header needs a second byte.
+	long := bytes.Repeat([]byte("gols "), 100)
+
+	gitDir := t.TempDir()
+	objects := []packObject{
+		{kind: 3, data: base, content: base, typ: "blob"},
+		{kind: 6, data: delta, base: 0, content: changed, typ: "blob"},
+		{kind: 3, data: long, content: long, typ: "blob"},
+	}
+	writeGitPack(t, gitDir, objects)
+
+	store := newGitObjectStore(gitDir)
+	for _, object := range objects {
+		hash := gitObjectHash(object.typ, object.content)
+		typ, data, err := store.read(hash)
+		if err != nil || typ != object.typ || !bytes.Equal(data, object.content) {
+			t.Errorf("read(%s) = %q, %q, %v, want %q, %q", hash, typ, data, err, object.typ, object.content)
+		}
+	}
+	if _, _, err := store.read(gitObjectHash("blob", []byte("missing"))); err != errGitObjectNotFound {
+		t.Errorf("read of a missing object: %v, want %v", err, errGitObjectNotFound)
+	}
+}
+
+func TestLoadGitPackRejects(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string][]byte{
+		"empty":     nil,
+		"version 1": make([]byte, 8+256*4),
+		"truncated": concat([]byte{0xff, 't', 'O', 'c', 0, 0, 0, 2}, bytes.Repeat([]byte{0, 0, 0, 1}, 256)),
+	}
+	for name, data := range tests {
+		index := filepath.Join(dir, name+".idx")
+		if err := os.WriteFile(index, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadGitPack(index); err == nil {
+			t.Errorf("%s: loadGitPack succeeded", name)
+		}
+	}
+}
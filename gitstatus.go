@@ -0,0 +1,484 @@
+package gols
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// gitIndexEntry is the part of a .git/index entry needed to tell whether
+// the working tree file changed.
+type gitIndexEntry struct {
+	hash      string
+	mode      uint32
+	size      uint32
+	mtime     uint32
+	mtimeNsec uint32
+	stage     int
+}
+
+// gitRepo holds the index and HEAD tree of one repository along with
+// the statuses computed so far.
+type gitRepo struct {
+	ls       *lister
+	root     string
+	gitDir   string
+	index    map[string]gitIndexEntry
+	head     map[string]string
+	children map[string]map[string]bool
+	statuses map[string]string
+}
+
+// gitStatusColumn returns the colored status of name in directory
+// followed by a space, or an empty string when --git is off.
+func (ls *lister) gitStatusColumn(directory, name string, isDir bool) string {
+	if !ls.opts.GitStatus {
+		return ""
+	}
+	return formatGitStatus(ls.gitStatus(directory, name, isDir)) + " "
+}
+
+// gitStatusWidth is the number of columns gitStatusColumn takes up.
+func (ls *lister) gitStatusWidth() int {
+	if !ls.opts.GitStatus {
+		return 0
+	}
+	return 3
+}
+
+func formatGitStatus(status string) string {
+	switch status {
+	case "??":
+		return red + status + reset
+	case "!!":
+		return gray + status + reset
+	}
+	return green + status[:1] + reset + red + status[1:] + reset
+}
+
+// gitStatus returns the short status of an entry: the first character
+// compares the index with HEAD and the second the working tree with the
+// index. Directories aggregate the status of their contents. Entries
+// outside a repository are reported as clean.
+func (ls *lister) gitStatus(directory, name string, isDir bool) string {
+	dir, err := filepath.Abs(directory)
+	if err != nil {
+		return "  "
+	}
+	if name == ".git" {
+		return "!!"
+	}
+
+	repo := ls.gitRepoFor(dir)
+	if repo == nil {
+		return "  "
+	}
+	rel, err := filepath.Rel(repo.root, filepath.Join(dir, name))
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "  "
+	}
+	rel = filepath.ToSlash(rel)
+	// Git never looks at its own directory, and neither does -r -a.
+	if rel == ".git" || strings.HasPrefix(rel, ".git/") {
+		return "!!"
+	}
+
+	if isDir {
+		if rel == "." {
+			return repo.dirStatus("")
+		}
+		return repo.dirStatus(rel)
+	}
+	return repo.fileStatus(rel)
+}
+
+// gitBranchName returns the branch of the repository rooted at the
+// directory name in directory, the short commit hash when HEAD is
+// detached, or an empty string if it is not a repository root.
+func gitBranchName(directory, name string) string {
+	gitDir := findGitDir(filepath.Join(directory, name))
+	if gitDir == "" {
+		return ""
+	}
+	branch, hash := readHead(gitDir)
+	if branch == "" && len(hash) >= 7 {
+		branch = hash[:7]
+	}
+	return branch
+}
+
+// gitBranchLabel formats the branch shown after a repository's icon and
+// returns it with its width in columns.
+func (ls *lister) gitBranchLabel(directory, name string) (string, int) {
+	if !ls.opts.GitStatus {
+		return "", 0
+	}
+	branch := gitBranchName(directory, name)
+	if branch == "" {
+		return "", 0
+	}
+	return " " + magenta + "\ue0a0 " + branch + reset, len([]rune(branch)) + 3
+}
+
+func (ls *lister) gitRepoFor(dir string) *gitRepo {
+	root := findRepoRoot(dir)
+	if root == "" {
+		return nil
+	}
+	if repo, found := ls.cache.gitRepos[root]; found {
+		return repo
+	}
+
+	repo := &gitRepo{
+		ls:       ls,
+		root:     root,
+		index:    map[string]gitIndexEntry{},
+		head:     map[string]string{},
+		children: map[string]map[string]bool{},
+		statuses: map[string]string{},
+	}
+	ls.cache.gitRepos[root] = repo
+
+	repo.gitDir = findGitDir(root)
+	if repo.gitDir == "" {
+		return repo
+	}
+	if index, err := readGitIndex(filepath.Join(repo.gitDir, "index")); err == nil {
+		repo.index = index
+	}
+
+	if _, head := readHead(repo.gitDir); head != "" {
+		store := newGitObjectStore(repo.gitDir)
+		if tree, err := store.commitTree(head); err == nil {
+			store.flattenTree(tree, "", repo.head)
+		}
+	}
+
+	for rel := range repo.index {
+		repo.addChild(rel)
+	}
+	for rel := range repo.head {
+		repo.addChild(rel)
+	}
+	return repo
+}
+
+// addChild records rel and each of its parent directories, so that
+// deleted files still show up in their directory's status.
+func (r *gitRepo) addChild(rel string) {
+	isDir := false
+	for rel != "." && rel != "" {
+		parent := path.Dir(rel)
+		if parent == "." {
+			parent = ""
+		}
+		if r.children[parent] == nil {
+			r.children[parent] = map[string]bool{}
+		}
+		r.children[parent][path.Base(rel)] = r.children[parent][path.Base(rel)] || isDir
+		rel, isDir = parent, true
+	}
+}
+
+func (r *gitRepo) fileStatus(rel string) string {
+	if status, found := r.statuses[rel]; found {
+		return status
+	}
+
+	entry, inIndex := r.index[rel]
+	headHash, inHead := r.head[rel]
+
+	x := byte(' ')
+	switch {
+	case inIndex && entry.stage != 0:
+		r.statuses[rel] = "UU"
+		return "UU"
+	case inIndex && !inHead:
+		x = 'A'
+	case inIndex && entry.hash != headHash:
+		x = 'M'
+	case !inIndex && inHead:
+		x = 'D'
+	}
+
+	y := byte(' ')
+	full := filepath.Join(r.root, filepath.FromSlash(rel))
+	info, err := os.Lstat(full)
+	switch {
+	case err != nil:
+		if inIndex {
+			y = 'D'
+		}
+	case !inIndex:
+		// A file staged for deletion and then recreated is both "D " and
+		// "??" to git; it shows here as "D?", like a directory would.
+		ignored := r.ls.gitIgnored(filepath.Dir(full), filepath.Base(full), false)
+		switch {
+		case x == ' ' && ignored:
+			r.statuses[rel] = "!!"
+			return "!!"
+		case x == ' ':
+			r.statuses[rel] = "??"
+			return "??"
+		case !ignored:
+			y = '?'
+		}
+	case worktreeChanged(entry, info, full):
+		y = 'M'
+	}
+
+	status := string([]byte{x, y})
+	r.statuses[rel] = status
+	return status
+}
+
+// worktreeChanged compares a file with its index entry, trusting equal
+// size and modification time and hashing the content otherwise.
+func worktreeChanged(entry gitIndexEntry, info os.FileInfo, full string) bool {
+	const (
+		modeTypeMask = 0170000
+		modeSymlink  = 0120000
+		modeRegular  = 0100000
+		modeGitlink  = 0160000
+	)
+
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		if entry.mode&modeTypeMask != modeSymlink {
+			return true
+		}
+	case info.Mode().IsRegular():
+		if entry.mode&modeTypeMask != modeRegular || (entry.mode&0100 != 0) != (info.Mode()&0100 != 0) {
+			return true
+		}
+	case info.IsDir():
+		if entry.mode&modeTypeMask != modeGitlink {
+			return true
+		}
+		// A submodule changes when its checkout moves to another commit;
+		// one that was never checked out is left alone, as git does.
+		gitDir := findGitDir(full)
+		if gitDir == "" {
+			return false
+		}
+		_, head := readHead(gitDir)
+		return head != "" && head != entry.hash
+	default:
+		return true
+	}
+
+	if uint32(info.Size()) != entry.size {
+		return true
+	}
+	mtime := info.ModTime()
+	if uint32(mtime.Unix()) == entry.mtime && uint32(mtime.Nanosecond()) == entry.mtimeNsec {
+		return false
+	}
+
+	hash, err := hashGitBlob(full, info)
+	return err != nil || hash != entry.hash
+}
+
+// hashGitBlob computes the object name git would give the file.
+func hashGitBlob(full string, info os.FileInfo) (string, error) {
+	h := sha1.New()
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(full)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "blob %d\x00%s", len(target), target)
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	f, err := os.Open(full)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	fmt.Fprintf(h, "blob %d\x00", info.Size())
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// dirStatus aggregates the status of everything below rel, both what is
+// on disk and what the index and HEAD still know about.
+func (r *gitRepo) dirStatus(rel string) string {
+	key := rel + "/"
+	if status, found := r.statuses[key]; found {
+		return status
+	}
+
+	full := filepath.Join(r.root, filepath.FromSlash(rel))
+	if rel != "" && r.ls.gitIgnored(filepath.Dir(full), filepath.Base(full), true) {
+		r.statuses[key] = "!!"
+		return "!!"
+	}
+
+	// A submodule is a single index entry recording its commit, so it
+	// has the status of a file rather than that of its contents.
+	if entry, found := r.index[rel]; found && entry.mode&0170000 == 0160000 {
+		status := r.fileStatus(rel)
+		r.statuses[key] = status
+		return status
+	}
+
+	// A nested repository that is not a submodule is untracked as a
+	// whole, like git reports it.
+	if _, tracked := r.children[rel]; rel != "" && !tracked {
+		if _, err := os.Lstat(filepath.Join(full, ".git")); err == nil {
+			r.statuses[key] = "??"
+			return "??"
+		}
+	}
+
+	children := map[string]bool{}
+	for name, isDir := range r.children[rel] {
+		children[name] = isDir
+	}
+	if entries, err := os.ReadDir(full); err == nil {
+		for _, entry := range entries {
+			if entry.Name() != ".git" {
+				children[entry.Name()] = entry.IsDir()
+			}
+		}
+	}
+
+	var statuses []string
+	for name, isDir := range children {
+		child := path.Join(rel, name)
+		if isDir {
+			statuses = append(statuses, r.dirStatus(child))
+		} else {
+			statuses = append(statuses, r.fileStatus(child))
+		}
+	}
+
+	status := aggregateGitStatus(statuses)
+	r.statuses[key] = status
+	return status
+}
+
+// aggregateGitStatus combines the statuses of a directory's contents,
+// keeping the most significant change on each side.
+func aggregateGitStatus(statuses []string) string {
+	const rank = "UMDA?"
+	stronger := func(a, b byte) byte {
+		i, j := strings.IndexByte(rank, a), strings.IndexByte(rank, b)
+		if j >= 0 && (i < 0 || j < i) {
+			return b
+		}
+		return a
+	}
+
+	x, y := byte(' '), byte(' ')
+	ignored, untracked, tracked := false, false, false
+	for _, status := range statuses {
+		switch status {
+		case "!!":
+			ignored = true
+		case "??":
+			untracked = true
+		default:
+			tracked = true
+			x = stronger(x, status[0])
+			y = stronger(y, status[1])
+		}
+	}
+
+	switch {
+	case untracked && !tracked:
+		return "??"
+	case ignored && !untracked && !tracked:
+		return "!!"
+	case untracked && y == ' ':
+		y = '?'
+	}
+	return string([]byte{x, y})
+}
+
+// readGitIndex parses versions 2 to 4 of the index file format.
+func readGitIndex(name string) (map[string]gitIndexEntry, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 12 || string(data[:4]) != "DIRC" {
+		return nil, fmt.Errorf("%s is not a git index", name)
+	}
+	version := binary.BigEndian.Uint32(data[4:8])
+	if version < 2 || version > 4 {
+		return nil, fmt.Errorf("unsupported index version %d", version)
+	}
+	count := int(binary.BigEndian.Uint32(data[8:12]))
+
+	entries := make(map[string]gitIndexEntry, count)
+	pos := 12
+	previous := ""
+	for i := 0; i < count; i++ {
+		if pos+62 > len(data) {
+			return nil, fmt.Errorf("truncated index %s", name)
+		}
+		start := pos
+		entry := gitIndexEntry{
+			mtime:     binary.BigEndian.Uint32(data[pos+8:]),
+			mtimeNsec: binary.BigEndian.Uint32(data[pos+12:]),
+			mode:      binary.BigEndian.Uint32(data[pos+24:]),
+			size:      binary.BigEndian.Uint32(data[pos+36:]),
+			hash:      hex.EncodeToString(data[pos+40 : pos+60]),
+		}
+		flags := binary.BigEndian.Uint16(data[pos+60:])
+		entry.stage = int(flags>>12) & 3
+		pos += 62
+		if flags&0x4000 != 0 {
+			pos += 2
+		}
+		if pos >= len(data) {
+			return nil, fmt.Errorf("truncated index %s", name)
+		}
+
+		var entryName string
+		if version == 4 {
+			r := bytes.NewReader(data[pos:])
+			strip, err := readOffsetVarint(r)
+			if err != nil || int(strip) > len(previous) {
+				return nil, fmt.Errorf("corrupt index %s", name)
+			}
+			pos += len(data[pos:]) - r.Len()
+			end := bytes.IndexByte(data[pos:], 0)
+			if end < 0 {
+				return nil, fmt.Errorf("corrupt index %s", name)
+			}
+			entryName = previous[:len(previous)-int(strip)] + string(data[pos:pos+end])
+			pos += end + 1
+		} else {
+			end := bytes.IndexByte(data[pos:], 0)
+			if end < 0 {
+				return nil, fmt.Errorf("corrupt index %s", name)
+			}
+			entryName = string(data[pos : pos+end])
+			pos += end + 1
+			for (pos-start)%8 != 0 {
+				pos++
+			}
+			if pos > len(data) {
+				return nil, fmt.Errorf("truncated index %s", name)
+			}
+		}
+		previous = entryName
+
+		if existing, found := entries[entryName]; !found || existing.stage == 0 || entry.stage != 0 {
+			entries[entryName] = entry
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,320 @@
+package gols
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// indexEntry is one entry written by writeGitIndex.
+type indexEntry struct {
+	name  string
+	hash  string
+	mode  uint32
+	size  uint32
+	mtime uint32
+	stage int
+}
+
+// writeGitIndex encodes entries, sorted by name, as an index file of the
+// given version and returns its path.
+func writeGitIndex(t *testing.T, version uint32, entries []indexEntry) string {
+	t.Helper()
+	var buf bytes.Buffer
+	buf.WriteString("DIRC")
+	binary.Write(&buf, binary.BigEndian, version)
+	binary.Write(&buf, binary.BigEndian, uint32(len(entries)))
+
+	previous := ""
+	for _, entry := range entries {
+		start := buf.Len()
+		fields := []uint32{0, 0, entry.mtime, 0, 0, 0, entry.mode, 0, 0, entry.size}
+		for _, field := range fields {
+			binary.Write(&buf, binary.BigEndian, field)
+		}
+		raw, _ := hex.DecodeString(entry.hash)
+		buf.Write(raw)
+		binary.Write(&buf, binary.BigEndian, uint16(entry.stage<<12|min(len(entry.name), 0xfff)))
+
+		if version == 4 {
+			common := 0
+			for common < len(previous) && common < len(entry.name) && previous[common] == entry.name[common] {
+				common++
+			}
+			strip := len(previous) - common
+			encoded := []byte{byte(strip & 0x7f)}
+			for strip >>= 7; strip > 0; strip >>= 7 {
+				strip--
+				encoded = append([]byte{byte(0x80 | strip&0x7f)}, encoded...)
+			}
+			buf.Write(encoded)
+			buf.WriteString(entry.name[common:])
+			buf.WriteByte(0)
+		} else {
+			buf.WriteString(entry.name)
+			buf.WriteByte(0)
+			for (buf.Len()-start)%8 != 0 {
+				buf.WriteByte(0)
+			}
+		}
+		previous = entry.name
+	}
+
+	name := filepath.Join(t.TempDir(), "index")
+	if err := os.WriteFile(name, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestReadGitIndex(t *testing.T) {
+	const (
+		hashA = "e69de29bb2d1d6434b8b29ae775ad8c2e48c5391"
+		hashB = "3b18e512dba79e4c8300dd08aeb37f8e728b8dad"
+		hashC = "d00491fd7e5bb6fa28c517a0bb32b8b506539d4d"
+	)
+	entries := []indexEntry{
+		{name: "README.md", hash: hashA, mode: 0100644, mtime: 1700000000},
+		{name: "cmd/gols/main.go", hash: hashB, mode: 0100755, size: 12},
+		{name: "cmd/gols/main_test.go", hash: hashC, mode: 0100644, size: 2},
+		{name: "conflict", hash: hashA, mode: 0100644, stage: 1},
+		{name: "conflict", hash: hashB, mode: 0100644, stage: 2},
+		{name: "conflict", hash: hashC, mode: 0100644, stage: 3},
+	}
+
+	for _, version := range []uint32{2, 3, 4} {
+		index, err := readGitIndex(writeGitIndex(t, version, entries))
+		if err != nil {
+			t.Fatalf("version %d: %v", version, err)
+		}
+		if len(index) != 4 {
+			t.Errorf("version %d: %d entries, want 4: %v", version, len(index), index)
+		}
+		for _, want := range entries[:3] {
+			got := index[want.name]
+			if got.hash != want.hash || got.mode != want.mode || got.size != want.size || got.mtime != want.mtime || got.stage != 0 {
+				t.Errorf("version %d: %s = %+v, want %+v", version, want.name, got, want)
+			}
+		}
+		if index["conflict"].stage == 0 {
+			t.Errorf("version %d: conflict lost its stage: %+v", version, index["conflict"])
+		}
+	}
+}
+
+func TestReadGitIndexRejects(t *testing.T) {
+	dir := t.TempDir()
+	valid := readFileOrFatal(t, writeGitIndex(t, 2, []indexEntry{{name: "a", hash: "e69de29bb2d1d6434b8b29ae775ad8c2e48c5391"}}))
+	tests := map[string][]byte{
+		"empty":     nil,
+		"magic":     append([]byte("DIRX"), valid[4:]...),
+		"version 1": append(append([]byte("DIRC"), 0, 0, 0, 1), valid[8:]...),
+		"version 5": append(append([]byte("DIRC"), 0, 0, 0, 5), valid[8:]...),
+		"truncated": valid[:40],
+		"unended":   valid[:12+62+1],
+	}
+	for name, data := range tests {
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if index, err := readGitIndex(file); err == nil {
+			t.Errorf("%s: readGitIndex = %v, want an error", name, index)
+		}
+	}
+}
+
+// TestReadGitIndexTruncated cuts an index with extended flags at every
+// length, which must fail rather than read past the end.
+func TestReadGitIndexTruncated(t *testing.T) {
+	valid := readFileOrFatal(t, writeGitIndex(t, 3, []indexEntry{{name: "a", hash: "e69de29bb2d1d6434b8b29ae775ad8c2e48c5391"}}))
+	entry := append([]byte{}, valid[12:12+62]...)
+	entry[60] |= 0x40
+	entry = append(entry, 0, 0, 'a', 0)
+	entry = append(entry, make([]byte, 8-len(entry)%8)...)
+	data := append(append([]byte{}, valid[:12]...), entry...)
+
+	dir := t.TempDir()
+	for n := len(data); n >= 0; n-- {
+		file := filepath.Join(dir, "index")
+		if err := os.WriteFile(file, data[:n], 0o644); err != nil {
+			t.Fatal(err)
+		}
+		index, err := readGitIndex(file)
+		if n == len(data) {
+			if err != nil || len(index) != 1 {
+				t.Fatalf("readGitIndex = %v, %v, want the entry a", index, err)
+			}
+		} else if err == nil {
+			t.Errorf("%d bytes: readGitIndex = %v, want an error", n, index)
+		}
+	}
+}
+
+func readFileOrFatal(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestAggregateGitStatus(t *testing.T) {
+	tests := []struct {
+		statuses []string
+		want     string
+	}{
+		{nil, "  "},
+		{[]string{"  ", "  "}, "  "},
+		{[]string{"??", "??"}, "??"},
+		{[]string{"!!"}, "!!"},
+		{[]string{"!!", "??"}, "??"},
+		{[]string{" M", "A "}, "AM"},
+		{[]string{" M", "??"}, " M"},
+		{[]string{"  ", "??"}, " ?"},
+		{[]string{"M ", "UU", " D"}, "UU"},
+		{[]string{"D?", "  "}, "D?"},
+	}
+	for _, test := range tests {
+		if got := aggregateGitStatus(test.statuses); got != test.want {
+			t.Errorf("aggregateGitStatus(%q) = %q, want %q", test.statuses, got, test.want)
+		}
+	}
+}
+
+// gitCommand runs git in dir, skipping the test when git is missing.
+func gitCommand(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=gols", "GIT_AUTHOR_EMAIL=gols@example.com",
+		"GIT_COMMITTER_NAME=gols", "GIT_COMMITTER_EMAIL=gols@example.com",
+		"GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return string(out)
+}
+
+// TestGitRepository checks the parsers against what git itself wrote:
+// a packed history with deltas and a version 4 index.
+func TestGitRepository(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q")
+
+	text := strings.Repeat("a line that stays the same in every version\n", 50)
+	for i, change := range []string{"first\n", "second\n", "third\n"} {
+		if err := os.MkdirAll(filepath.Join(dir, "sub"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte(text+change), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "sub", "n"), []byte{byte('0' + i)}, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		gitCommand(t, dir, "add", "-A")
+		gitCommand(t, dir, "commit", "-q", "-m", change)
+	}
+	gitCommand(t, dir, "gc", "-q", "--aggressive")
+	gitCommand(t, dir, "update-index", "--index-version", "4")
+
+	gitDir := findGitDir(dir)
+	_, head := readHead(gitDir)
+	if want := strings.TrimSpace(gitCommand(t, dir, "rev-parse", "HEAD")); head != want {
+		t.Fatalf("readHead = %s, want %s", head, want)
+	}
+
+	store := newGitObjectStore(gitDir)
+	tree, err := store.commitTree(head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	if err := store.flattenTree(tree, "", files); err != nil {
+		t.Fatal(err)
+	}
+	index, err := readGitIndex(filepath.Join(gitDir, "index"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(gitCommand(t, dir, "ls-tree", "-r", "HEAD")), "\n")
+	if len(files) != len(lines) || len(index) != len(lines) {
+		t.Errorf("%d files in HEAD and %d in the index, want %d", len(files), len(index), len(lines))
+	}
+	for _, line := range lines {
+		meta, name, _ := strings.Cut(line, "\t")
+		hash := strings.Fields(meta)[2]
+		if files[name] != hash {
+			t.Errorf("HEAD:%s = %s, want %s", name, files[name], hash)
+		}
+		if index[name].hash != hash {
+			t.Errorf("index %s = %s, want %s", name, index[name].hash, hash)
+		}
+	}
+
+	// Older versions of file.txt are stored as deltas of the newest.
+	for _, rev := range []string{"HEAD~1", "HEAD~2"} {
+		hash := strings.TrimSpace(gitCommand(t, dir, "rev-parse", rev+":file.txt"))
+		_, data, err := store.read(hash)
+		if want := gitCommand(t, dir, "cat-file", "blob", hash); err != nil || string(data) != want {
+			t.Errorf("%s:file.txt = %q, %v, want %q", rev, data, err, want)
+		}
+	}
+}
+
+// TestGitSubmodule checks that a submodule is tracked as the commit it
+// records rather than untracked like any other nested repository.
+func TestGitSubmodule(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib")
+	if err := os.MkdirAll(lib, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	commit := func(repo, name string) {
+		if err := os.WriteFile(filepath.Join(repo, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		gitCommand(t, repo, "add", "-A")
+		gitCommand(t, repo, "commit", "-q", "-m", name)
+	}
+	gitCommand(t, lib, "init", "-q")
+	commit(lib, "lib.go")
+	gitCommand(t, dir, "init", "-q")
+	commit(dir, "main.go")
+
+	steps := []struct {
+		change func()
+		want   string
+	}{
+		{func() {}, "  "},
+		{func() { commit(lib, "more.go") }, " M"},
+		{func() { gitCommand(t, dir, "add", "lib") }, "M "},
+		{func() { gitCommand(t, dir, "commit", "-q", "-m", "bump") }, "  "},
+	}
+	for i, step := range steps {
+		step.change()
+		ls, err := newLister(Options{GitStatus: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ls.gitStatus(dir, "lib", true); got != step.want {
+			t.Errorf("step %d: lib = %q, want %q", i, got, step.want)
+		}
+		if got := ls.gitStatus(dir, ".", true); got != step.want {
+			t.Errorf("step %d: repository = %q, want %q", i, got, step.want)
+		}
+	}
+}
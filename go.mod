@@ -1,3 +1,7 @@
-module gols
+module github.com/elbachir-one/gols
 
 go 1.22
+
+require golang.org/x/crypto v0.32.0
+
+require golang.org/x/sys v0.29.0 // indirect
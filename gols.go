@@ -1,45 +1,18 @@
-package main
+package gols
 
 import (
+    "context"
+    "errors"
     "fmt"
-    "log"
+    "io"
     "os"
-    "os/user"
     "path/filepath"
     "sort"
-    "strconv"
     "strings"
     "syscall"
     "unsafe"
 )
 
-var (
-    longListing         bool
-    humanReadable       bool
-    fileSize            bool
-    orderBySize         bool
-    orderByTime         bool
-    showOnlySymlinks    bool
-    showHidden          bool
-    recursiveListing    bool
-    dirOnLeft           bool
-    showSummary         bool
-    showVersion         bool
-    maxDepth            int = -1
-    listDirsOnly        bool
-    listFilesOnly       bool
-    listHiddenOnly      bool
-    oneColumn           bool
-    fileExtensions      []string
-    extFlag             string
-    excludeExtensions   bool
-    excludedExts        []string
-    onlyPermissions     bool
-    showOwner           bool
-    getTime             bool
-    showGroup           bool
-)
-
 type winsize struct {
     Row    uint16
     Col    uint16
@@ -51,94 +24,109 @@ type fakeDirEntry struct {
     info os.FileInfo
 }
 
-func main() {
-    args := os.Args[1:]
-    nonFlagArgs, hasFlags, hasSpecificFlags := parseFlags(args)
+// errNoFiles is returned by readEntries when the name filters leave
+// nothing to list.
+var errNoFiles = errors.New("no files found")
 
-    if showVersion {
-        fmt.Println(version)
-        return
+// listDirectory reads, filters and sorts directory (or a single file)
+// and prints it with the renderer the options select. It reports false
+// when nothing was left to list.
+func (ls *lister) listDirectory(w io.Writer, directory string) (bool, error) {
+    if ls.opts.RecursiveFlat {
+        found, err := ls.printSections(w, directory)
+        if err == nil && !found {
+            fmt.Fprintln(w, "No files found.")
+        }
+        return found, err
     }
 
-    var directory string
-
-    if len(nonFlagArgs) > 0 {
-        directory = nonFlagArgs[0]
+    l, err := ls.newListing(context.Background(), directory)
+    if err == errNoFiles {
+        fmt.Fprintln(w, "No files found.")
+        return false, nil
     }
-
-    if len(nonFlagArgs) > 1 {
-        fileExtensions = strings.Split(nonFlagArgs[1], ",")
-    } else if extFlag != "" {
-        fileExtensions = strings.Split(extFlag, ",")
+    if err != nil {
+        return false, err
     }
 
-    if directory == "" {
-        directory = "."
+    if ls.opts.SummaryOnly {
+        l.summary().print(w)
+        return true, nil
     }
 
+    renderer, _ := LookupRenderer(ls.opts.FormatName())
+    return true, renderer.Render(w, l)
+}
+
+// readEntries reads directory, or the single file it names, and applies
+// the filters and sort order of the options. It also returns the
+// directory the entries are in, which for a file is its parent, and how
+// many hidden entries were left out.
+func (ls *lister) readEntries(directory string) ([]os.DirEntry, string, int, error) {
     var files []os.DirEntry
-    var err error
-    var info os.FileInfo
 
-    info, err = os.Stat(directory)
+    if err := ls.openArchive(directory); err != nil {
+        return nil, "", 0, err
+    }
+
+    info, err := ls.statPath(directory)
     if err != nil {
-        log.Fatalf("Error: %v", err)
+        return nil, "", 0, err
     }
 
     if info.IsDir() {
-        files, err = os.ReadDir(directory)
+        files, err = ls.readDir(directory)
         if err != nil {
-            log.Fatal(err)
+            return nil, "", 0, err
         }
     } else {
         files = []os.DirEntry{&fakeDirEntry{info}}
         directory = filepath.Dir(directory)
     }
 
-    if len(fileExtensions) > 0 {
-        extSet := make(map[string]struct{})
-        for _, ext := range fileExtensions {
-            ext = strings.TrimPrefix(ext, ".")
-            extSet[ext] = struct{}{}
-        }
-        var filteredFiles []os.DirEntry
-        for _, file := range files {
-            ext := strings.TrimPrefix(filepath.Ext(file.Name()), ".")
-            if _, found := extSet[ext]; found {
-                filteredFiles = append(filteredFiles, file)
-            }
+    if ls.matcher.active() && !ls.recursive {
+        relDir := ""
+        if ls.treeRoot != "" {
+            // A section of -R, matched by its path below the root.
+            relDir = ls.treeRelPath(directory, "")
         }
-        files = filteredFiles
+        files = ls.filterNames(files, relDir)
     }
 
     if len(files) == 0 {
-        fmt.Println("No files found.")
-        return
+        return nil, "", 0, errNoFiles
     }
 
-    if !showHidden {
-        files = filterHidden(files)
+    hidden := 0
+    if !ls.showHidden {
+        shown := filterHidden(files)
+        hidden = len(files) - len(shown)
+        files = shown
     }
 
-    if showOnlySymlinks {
-        files = filterSymlinks(files, directory)
+    if ls.opts.Gitignore == "hide" {
+        files = ls.filterGitignored(files, directory)
     }
 
-    if listDirsOnly && listHiddenOnly {
+    if ls.opts.SymlinksOnly {
+        files = ls.filterSymlinks(files, directory)
+    }
+
+    if ls.opts.DirsOnly && ls.opts.HiddenOnly {
         files = filterHiddenOnly(files)
-    } else if listDirsOnly {
+    } else if ls.opts.DirsOnly {
         files = filterDirectories(files)
-    } else if listFilesOnly {
+    } else if ls.opts.FilesOnly {
         files = filterNonDirectories(files)
-    } else if listHiddenOnly {
+    } else if ls.opts.HiddenOnly {
         files = filterHiddenOnly(files)
     }
 
-    if len(excludedExts) > 0 {
-        files = filterExcludedExtensions(files, excludedExts)
+    if len(ls.opts.Predicates) > 0 {
+        files = ls.filterPredicates(files, directory)
     }
 
-    if orderBySize {
+    if ls.opts.SortBySize {
         sort.Slice(files, func(i, j int) bool {
             info1, _ := files[i].Info()
             info2, _ := files[j].Info()
@@ -146,7 +134,7 @@ func main() {
         })
     }
 
-    if orderByTime {
+    if ls.opts.SortByTime {
         sort.Slice(files, func(i, j int) bool {
             info1, _ := files[i].Info()
             info2, _ := files[j].Info()
@@ -154,27 +142,7 @@ func main() {
         })
     }
 
-    if showGroup {
-        printGroups(files, directory)
-    } else if onlyPermissions {
-        printPermissions(files, directory)
-    } else if showOwner {
-        printOwner(files, directory)
-    } else if getTime {
-        printTime(files, directory)
-    } else if recursiveListing {
-        printTree(directory, "", true, 0, maxDepth)
-    } else if longListing {
-        printLongListing(files, directory, humanReadable)
-    } else if fileSize {
-        getFileSize(files, directory, humanReadable, dirOnLeft)
-    } else {
-        printFilesInColumns(files, directory, dirOnLeft, showSummary)
-    }
-
-    if (hasSpecificFlags && !longListing) || !hasFlags {
-        fmt.Println()
-    }
+    return files, directory, hidden, nil
 }
 
 func (f *fakeDirEntry) Name() string               { return f.info.Name() }
@@ -182,16 +150,6 @@ func (f *fakeDirEntry) IsDir() bool                { return f.info.IsDir() }
 func (f *fakeDirEntry) Type() os.FileMode          { return f.info.Mode().Type() }
 func (f *fakeDirEntry) Info() (os.FileInfo, error) { return f.info, nil }
 
-func filterByExtension(files []os.DirEntry, extension string) []os.DirEntry {
-    var filtered []os.DirEntry
-    for _, file := range files {
-        if strings.TrimPrefix(filepath.Ext(file.Name()), ".") == extension {
-            filtered = append(filtered, file)
-        }
-    }
-    return filtered
-}
-
 func filterNonDirectories(files []os.DirEntry) []os.DirEntry {
     var nonDirs []os.DirEntry
     for _, file := range files {
@@ -222,24 +180,6 @@ func filterFiles(entries []os.DirEntry) []os.DirEntry {
     return result
 }
 
-func filterExcludedExtensions(files []os.DirEntry, excludedExts []string) []os.DirEntry {
-    var filteredFiles []os.DirEntry
-    for _, file := range files {
-        ext := strings.TrimPrefix(filepath.Ext(file.Name()), ".")
-        exclude := false
-        for _, excludedExt := range excludedExts {
-            if ext == excludedExt {
-                exclude = true
-                break
-            }
-        }
-        if !exclude {
-            filteredFiles = append(filteredFiles, file)
-        }
-    }
-    return filteredFiles
-}
-
 func filterHiddenOnly(files []os.DirEntry) []os.DirEntry {
     var hiddenFiles []os.DirEntry
     for _, file := range files {
@@ -250,22 +190,6 @@ func filterHiddenOnly(files []os.DirEntry) []os.DirEntry {
     return hiddenFiles
 }
 
-func filterByExtensions(files []os.DirEntry, extensions []string) []os.DirEntry {
-    var filtered []os.DirEntry
-    extMap := make(map[string]struct{})
-    for _, ext := range extensions {
-        ext = strings.TrimPrefix(ext, ".")
-        extMap[ext] = struct{}{}
-    }
-    for _, file := range files {
-        ext := strings.TrimPrefix(filepath.Ext(file.Name()), ".")
-        if _, found := extMap[ext]; found {
-            filtered = append(filtered, file)
-        }
-    }
-    return filtered
-}
-
 func getTerminalWidth() (int, error) {
     ws := &winsize{}
     _, _, err := syscall.Syscall(syscall.SYS_IOCTL, uintptr(syscall.Stdin), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(ws)))
@@ -275,10 +199,10 @@ func getTerminalWidth() (int, error) {
     return int(ws.Col), nil
 }
 
-func printPadding(name string, maxFileNameLength int) {
+func printPadding(w io.Writer, name string, maxFileNameLength int) {
     padding := maxFileNameLength - len(name) + 1
     for i := 0; i < padding; i++ {
-        fmt.Print(" ")
+        fmt.Fprint(w, " ")
     }
 }
 
@@ -289,23 +213,57 @@ func truncateName(name string, maxLength int) string {
     return name
 }
 
-func printFile(file os.DirEntry, directory string, maxLength int, dirOnLeft bool) {
-    info, err := file.Info()
-    if err != nil {
-        log.Fatal(err)
+// printFile prints the git status, icon and name of entry, with label
+// standing for the name.
+func (ls *lister) printFile(w io.Writer, entry Entry, label string, dirOnLeft bool) {
+    fmt.Fprint(w, ls.gitStatusColumn(entry.Dir, entry.Name, entry.IsDir()))
+
+    if entry.IsDir() && dirOnLeft {
+        icon := DirectoryIcon(entry.Name)
+        branch, _ := ls.gitBranchLabel(entry.Dir, entry.Name)
+        fmt.Fprint(w, blue + ls.withIcon(icon, ls.styledName(entry.file, entry.Dir, label)) + reset + branch)
+    } else if entry.IsDir() {
+        icon := DirectoryIcon(entry.Name)
+        branch, _ := ls.gitBranchLabel(entry.Dir, entry.Name)
+        name := blue + ls.styledName(entry.file, entry.Dir, label)
+        if !ls.opts.NoIcons {
+            name += blue + " " + icon
+        }
+        fmt.Fprint(w, name + reset + branch)
+    } else {
+        fmt.Fprint(w, entry.Icon + ls.styledName(entry.file, entry.Dir, label))
     }
+}
 
-    truncatedName := truncateName(file.Name(), maxLength)
+// styledName highlights name when --watch saw the entry change, dims it
+// when --gitignore=dim is active and git ignores it, and otherwise
+// returns it unchanged.
+func (ls *lister) styledName(file os.DirEntry, directory, name string) string {
+    if ls.watchHighlighted(directory, file.Name()) {
+        return "\033[30;43m" + name + reset
+    }
+    if ls.opts.Gitignore == "dim" && ls.gitIgnored(directory, file.Name(), file.IsDir()) {
+        return gray + name + reset
+    }
+    return name
+}
 
-    if file.IsDir() && dirOnLeft {
-        icon := getDirectoryIcon(file.Name())
-        fmt.Print(blue + icon + " " + truncatedName + reset)
-    } else if file.IsDir() {
-        icon := getDirectoryIcon(file.Name())
-        fmt.Print(blue + truncatedName + " " + icon + reset)
-    } else {
-        fmt.Print(getFileIcon(file, info.Mode(), directory) + truncatedName)
+// visibleWidth returns how many cells s takes on the terminal, leaving
+// out its colour escapes.
+func visibleWidth(s string) int {
+    width := 0
+    inEscape := false
+    for _, r := range s {
+        switch {
+        case inEscape:
+            inEscape = r != 'm'
+        case r == '\033':
+            inEscape = true
+        default:
+            width++
+        }
     }
+    return width
 }
 
 func truncateString(s string, maxLength int) string {
@@ -315,73 +273,83 @@ func truncateString(s string, maxLength int) string {
     return s
 }
 
-func getMaxNameLength(files []os.DirEntry) int {
+// getMaxNameLength returns the length of the longest path among
+// entries, as the flat renderers show them.
+func getMaxNameLength(entries []Entry) int {
     maxLen := 0
-    for _, file := range files {
-        if len(file.Name()) > maxLen {
-            maxLen = len(file.Name())
+    for _, entry := range entries {
+        if len(entry.Rel) > maxLen {
+            maxLen = len(entry.Rel)
         }
     }
     return maxLen
 }
 
-func printFilesInColumns(files []os.DirEntry, directory string, dirOnLeft bool, showSummary bool) {
-    if oneColumn {
-        for _, file := range files {
-            printFile(file, directory, getMaxNameLength(files), dirOnLeft)
-            fmt.Println()
-        }
-    } else {
-        terminalWidth, err := getTerminalWidth()
-        if err != nil {
-            fmt.Println("Error getting terminal width:", err)
-            return
-        }
+// renderGrid fills the width of the terminal with as many columns of
+// names as fit. Flat renderers show entries by their path relative to
+// the listed directory, which is just the name unless the listing is a
+// tree.
+func renderGrid(w io.Writer, l *Listing) error {
+    ls := l.lister()
+    terminalWidth, err := getTerminalWidth()
+    if err != nil {
+        fmt.Fprintln(w, "Error getting terminal width:", err)
+        return nil
+    }
 
-        maxFileNameLength := getMaxNameLength(files)
-        columnWidth := maxFileNameLength + 1
+    maxFileNameLength := getMaxNameLength(l.Entries)
+    maxLabelLength := maxFileNameLength
+    for _, entry := range l.Entries {
+        if _, width := ls.gitBranchLabel(entry.Dir, entry.Name); width > 0 && entry.IsDir() {
+            maxLabelLength = max(maxLabelLength, len(entry.Rel)+width)
+        }
+    }
+    columnWidth := maxLabelLength + 1 + ls.gitStatusWidth()
 
-        maxFilesInLine := terminalWidth / columnWidth
+    maxFilesInLine := terminalWidth / columnWidth
 
-        filesInLine := 0
+    filesInLine := 0
 
-        for _, file := range files {
-            printFile(file, directory, maxFileNameLength, dirOnLeft)
+    for _, entry := range l.Entries {
+        ls.printFile(w, entry, truncateName(entry.Rel, maxFileNameLength), l.Options.DirIconLeft)
 
-            filesInLine++
-            if filesInLine >= maxFilesInLine {
-                fmt.Println()
-                filesInLine = 0
-            } else {
-                printPadding(truncateName(file.Name(), maxFileNameLength), maxFileNameLength)
+        filesInLine++
+        if filesInLine >= maxFilesInLine {
+            fmt.Fprintln(w)
+            filesInLine = 0
+        } else {
+            label := truncateName(entry.Rel, maxFileNameLength)
+            if _, width := ls.gitBranchLabel(entry.Dir, entry.Name); entry.IsDir() {
+                label += strings.Repeat(" ", width)
             }
+            printPadding(w, label, maxLabelLength)
         }
     }
-
-    if showSummary {
-        fmt.Println()
-        fmt.Println()
-        printSummary(files, directory)
+    if filesInLine > 0 {
+        fmt.Fprintln(w)
     }
-}
 
-func listFilesWithExtension(dir string, ext string) ([]os.DirEntry, error) {
-    var result []os.DirEntry
+    writeSummary(w, l)
+    return nil
+}
 
-    entries, err := os.ReadDir(dir)
-    if err != nil {
-        return nil, err
+// renderOneline prints one entry per line, after the --checksum and
+// --kind columns when they are on.
+func renderOneline(w io.Writer, l *Listing) error {
+    ls := l.lister()
+    if ls.opts.Checksum != "" {
+        ls.loadChecksums(l.Entries)
     }
-
-    for _, entry := range entries {
-        if entry.IsDir() {
-            continue
-        }
-        if strings.HasSuffix(entry.Name(), "."+ext) {
-            result = append(result, entry)
-        }
+    kindWidth := ls.kindWidth(l.Entries)
+    for _, entry := range l.Entries {
+        fmt.Fprint(w, ls.checksumColumn(entry.Dir, entry.Name))
+        fmt.Fprint(w, ls.kindColumn(entry.file, entry.Dir, kindWidth))
+        ls.printFile(w, entry, entry.Rel, l.Options.DirIconLeft)
+        fmt.Fprintln(w)
     }
-    return result, nil
+
+    writeSummary(w, l)
+    return nil
 }
 
 func filterHidden(entries []os.DirEntry) []os.DirEntry {
@@ -394,218 +362,62 @@ func filterHidden(entries []os.DirEntry) []os.DirEntry {
     return result
 }
 
-func filterSymlinks(entries []os.DirEntry, dir string) []os.DirEntry {
+func (ls *lister) filterSymlinks(entries []os.DirEntry, dir string) []os.DirEntry {
     var result []os.DirEntry
     for _, entry := range entries {
         fullPath := filepath.Join(dir, entry.Name())
-        if info, err := os.Lstat(fullPath); err == nil && info.Mode()&os.ModeSymlink != 0 {
+        if info, err := ls.lstatPath(fullPath); err == nil && info.Mode()&os.ModeSymlink != 0 {
             result = append(result, entry)
         }
     }
     return result
 }
 
-func parseFlags(args []string) ([]string, bool, bool) {
-    var nonFlagArgs []string
-    hasFlags := false
-    hasSpecificFlags := false
-
-    for i := 0; i < len(args); i++ {
-        arg := args[i]
-
-        if len(arg) > 1 && arg[0] == '-' {
-            hasFlags = true
-            if len(arg) > 2 && arg[1] == '-' {
-                switch arg {
-                case "--version":
-                    showVersion = true
-                case "--help":
-                    showHelp()
-                    os.Exit(0)
-                default:
-                    fmt.Println("Unknown long flag:", arg)
-                    showHelp()
-                    os.Exit(1)
-                }
-            } else {
-
-                for j := 1; j < len(arg); j++ {
-                    switch arg[j] {
-                    case 'l':
-                        longListing = true
-                    case 'c':
-                        oneColumn = true
-                    case 'h':
-                        humanReadable = true
-                        hasSpecificFlags = true
-                    case 'g':
-                        showGroup = true
-                    case 's':
-                        fileSize = true
-                    case 'o':
-                        orderBySize = true
-                        hasSpecificFlags = true
-                    case 'p':
-                        onlyPermissions = true
-                    case 'O':
-                        showOwner = true
-                    case 't':
-                        orderByTime = true
-                        hasSpecificFlags = true
-                    case 'T':
-                        getTime = true
-                    case 'm':
-                        showOnlySymlinks = true
-                        hasSpecificFlags = true
-                    case 'a':
-                        showHidden = true
-                        hasSpecificFlags = true
-                    case 'A':
-                        listHiddenOnly = true
-                        showHidden = true
-                        hasSpecificFlags = true
-                    case 'r':
-                        recursiveListing = true
-                    case 'i':
-                        dirOnLeft = true
-                        hasSpecificFlags = true
-                        hasFlags = true
-                    case 'f':
-                        showSummary = true
-                        hasSpecificFlags = true
-                    case 'v':
-                        showVersion = true
-                    case 'D':
-                        listDirsOnly = true
-                        hasSpecificFlags = true
-                    case 'F':
-                        listFilesOnly = true
-                        hasSpecificFlags = true
-                    case 'x':
-                        if j+1 < len(arg) && (arg[j+1] < '0' || arg[j+1] > '9') {
-                            excludedExts = strings.Split(arg[j+1:], ",")
-                            hasSpecificFlags = true
-                            break
-                        } else if i+1 < len(args) && args[i+1][0] != '-' {
-                            excludedExts = strings.Split(args[i+1], ",")
-                            hasSpecificFlags = true
-                            i++
-                            break
-                        } else {
-                            fmt.Println("Missing value for -x")
-                            os.Exit(1)
-                        }
-                    case 'd':
-                        if j+1 < len(arg) && arg[j+1] >= '0' && arg[j+1] <= '9' {
-                            depthValue := arg[j+1:]
-                            maxDepthValue, err := strconv.Atoi(depthValue)
-                            if err != nil {
-                                fmt.Println("Invalid value for -d")
-                                os.Exit(1)
-                            }
-                            maxDepth = maxDepthValue
-                            hasSpecificFlags = true
-                            break
-                        } else if i+1 < len(args) {
-                            depthValue := args[i+1]
-                            maxDepthValue, err := strconv.Atoi(depthValue)
-                            if err != nil {
-                                fmt.Println("Invalid value for -d")
-                                os.Exit(1)
-                            }
-                            maxDepth = maxDepthValue
-                            hasSpecificFlags = true
-                            i++
-                            break
-                        } else {
-                            fmt.Println("Missing value for -d")
-                            os.Exit(1)
-                        }
-                    case 'e':
-                        if i+1 < len(args) {
-                            extFlag = args[i+1]
-                            i++
-                            hasSpecificFlags = true
-                        } else {
-                            fmt.Println("Missing value for -e")
-                            os.Exit(1)
-                        }
-                    default:
-                        showHelp()
-                        os.Exit(1)
-                    }
-                }
-            }
-        } else {
-            nonFlagArgs = append(nonFlagArgs, arg)
-        }
-    }
-    return nonFlagArgs, hasFlags, hasSpecificFlags
-}
-
-func showHelp() {
-    fmt.Println()
-    fmt.Println("Usage: gols [FLAG] [DIRECTORY] [FILES]")
-    fmt.Println()
-    fmt.Println("FLAGS:")
-    fmt.Println()
-    fmt.Println("	-? --help       Help")
-    fmt.Println()
-    fmt.Println("	-a              Show Hidden files")
-    fmt.Println("	-A              Show only hidden files and directories")
-    fmt.Println("	-e              Filter files based on extensions")
-    fmt.Println("	-f              Show summary of directories and files")
-    fmt.Println("	-F              List files only")
-    fmt.Println("	-c              Don't use spacing, print all files in one column")
-    fmt.Println("	-D              Only directories are showing")
-    fmt.Println("	-h              Human-readable file sizes")
-    fmt.Println("	-i              Show directory icon on left")
-    fmt.Println("	-l              Long listing format")
-    fmt.Println("	-m              Only symbolic links are showing")
-    fmt.Println("	-o              Sort by size")
-    fmt.Println("	-r d n          Tree like listing, set the depth of the directory tree (n is an integer)")
-    fmt.Println("	-s              Print files size")
-    fmt.Println("	-t              Order by time")
-    fmt.Println("	-v --version    Show version")
-    fmt.Println("	-x              Exclude specific extensions")
-    fmt.Println()
-}
-
-func getFileSize(files []os.DirEntry, directory string, humanReadable, dirOnLeft bool) {
+// sizePrefixes returns the size column -s shows in front of the name of
+// each entry, with its bar when --bars is on.
+func (ls *lister) sizePrefixes(entries []Entry, humanReadable bool) []string {
     const sizeFieldWidth = 10
     const spaceBetweenSizeAndIcon = 2
 
-    for _, file := range files {
-        info, err := file.Info()
-        if err != nil {
-            log.Fatal(err)
-        }
+    scale := ls.newBarScale(entries)
 
-        size := info.Size()
+    prefixes := make([]string, len(entries))
+    for i, entry := range entries {
+        size := ls.entrySize(entry)
         sizeStr := formatSize(size, humanReadable)
 
         sizeStr = fmt.Sprintf("%*s", sizeFieldWidth, sizeStr)
-
-        fmt.Print(sizeStr)
-        for i := 0; i < spaceBetweenSizeAndIcon; i++ {
-            fmt.Print(" ")
+        if ls.opts.Bars {
+            sizeStr += " " + scale.bar(size, entry.IsDir())
         }
+        prefixes[i] = sizeStr + strings.Repeat(" ", spaceBetweenSizeAndIcon)
+    }
+    return prefixes
+}
 
-        if file.IsDir() {
-            if dirOnLeft {
-                fmt.Println(iconDirectory + " " + blue + file.Name() + reset)
+func renderSizes(w io.Writer, l *Listing) error {
+    ls := l.lister()
+    prefixes := ls.sizePrefixes(l.Entries, l.Options.HumanReadable)
+
+    for i, entry := range l.Entries {
+        fmt.Fprint(w, prefixes[i])
+
+        name := ls.styledName(entry.file, entry.Dir, entry.Rel)
+        if entry.IsDir() {
+            if l.Options.DirIconLeft {
+                fmt.Fprintln(w, ls.withIcon(iconDirectory, blue + name + reset))
+            } else if ls.opts.NoIcons {
+                fmt.Fprintln(w, blue + name + reset)
             } else {
-                fmt.Println(blue + file.Name() + " " + iconDirectory + " " + reset)
+                fmt.Fprintln(w, blue + name + blue + " " + iconDirectory + " " + reset)
             }
         } else {
-            fmt.Println(getFileIcon(file, info.Mode(), directory) + " " + file.Name())
+            fmt.Fprintln(w, ls.withIcon(entry.Icon, name))
         }
     }
 
-    if showSummary {
-        fmt.Println()
-        printSummary(files, directory)
-    }
+    writeSummary(w, l)
+    return nil
 }
 
 func padRight(str string, length int) string {
@@ -653,125 +465,97 @@ func formatSize(size int64, humanReadable bool) string {
     }
 }
 
-func printPermissions(files []os.DirEntry, directory string) {
-    for _, file := range files {
-        info, err := file.Info()
-        if err != nil {
-            log.Fatal(err)
-        }
-
-        permissions := formatPermissions(file, info.Mode(), directory)
+func renderPermissions(w io.Writer, l *Listing) error {
+    ls := l.lister()
+    for _, entry := range l.Entries {
+        permissions := ls.formatPermissions(entry.file, entry.Info.Mode(), entry.Dir)
         permissions = green + permissions + reset
 
-        iconAndName := getFileIcon(file, info.Mode(), directory) + " " + file.Name()
+        iconAndName := ls.withIcon(entry.Icon, ls.styledName(entry.file, entry.Dir, entry.Rel))
 
-        fmt.Printf("%s %s\n", permissions, iconAndName)
+        fmt.Fprintf(w, "%s %s\n", permissions, iconAndName)
     }
 
-    if showSummary {
-        fmt.Println()
-        printSummary(files, directory)
-    }
+    writeSummary(w, l)
+    return nil
 }
 
-func printOwner(files []os.DirEntry, directory string) {
-    for _, file := range files {
-        info, err := file.Info()
-        if err != nil {
-            log.Fatal(err)
-        }
+func renderOwners(w io.Writer, l *Listing) error {
+    ls := l.lister()
+    for _, entry := range l.Entries {
+        owner := ownerName(entry.Info)
 
-        owner, err := user.LookupId(fmt.Sprintf("%d", info.Sys().(*syscall.Stat_t).Uid))
-        if err != nil {
-            log.Fatal(err)
-        }
+        ownerStr := cyan + owner + reset
+        fileName := ls.styledName(entry.file, entry.Dir, entry.Rel)
 
-        ownerStr := cyan + owner.Username + reset
-        icon := getFileIcon(file, info.Mode(), directory)
-        fileName := file.Name()
-
-        fmt.Printf("%s %s %s\n", ownerStr, icon, fileName)
-    }
-    if showSummary {
-        fmt.Println()
-        printSummary(files, directory)
+        fmt.Fprintf(w, "%s %s\n", ownerStr, ls.withIcon(entry.Icon, fileName))
     }
-}
 
-func printTime(files []os.DirEntry, directory string) {
-    for _, file := range files {
-        info, err := file.Info()
-        if err != nil {
-            log.Fatal(err)
-        }
+    writeSummary(w, l)
+    return nil
+}
 
-        modTime := info.ModTime()
+func renderTimes(w io.Writer, l *Listing) error {
+    ls := l.lister()
+    for _, entry := range l.Entries {
+        modTime := entry.Info.ModTime()
         timeStr := modTime.Format("15:04:05")
         dateStr := modTime.Format("2006-01-02")
-        icon := getFileIcon(file, info.Mode(), directory)
-        fileName := file.Name()
+        fileName := ls.styledName(entry.file, entry.Dir, entry.Rel)
 
-        fmt.Printf("%s %s %s %s\n", dateStr, timeStr, icon, fileName)
+        fmt.Fprintf(w, "%s %s %s\n", dateStr, timeStr, ls.withIcon(entry.Icon, fileName))
     }
-    if showSummary {
-        fmt.Println()
-        printSummary(files, directory)
-    }
-}
 
-func printGroups(files []os.DirEntry, directory string) {
-    maxLen := map[string]int{
-        "group": 0,
-    }
-
-    var filteredFiles []os.DirEntry
-    for _, file := range files {
-        info, err := file.Info()
-        if err != nil {
-            log.Fatal(err)
-        }
-
-        group, err := user.LookupGroupId(fmt.Sprintf("%d", info.Sys().(*syscall.Stat_t).Gid))
-        if err != nil {
-            log.Fatal(err)
-        }
-
-        maxLen["group"] = max(maxLen["group"], len(group.Name))
+    writeSummary(w, l)
+    return nil
+}
 
-        filteredFiles = append(filteredFiles, file)
+func renderGroups(w io.Writer, l *Listing) error {
+    ls := l.lister()
+    groupWidth := 0
+    for _, entry := range l.Entries {
+        groupWidth = max(groupWidth, len(groupName(entry.Info)))
     }
 
-    for _, file := range filteredFiles {
-        info, err := file.Info()
-        if err != nil {
-            log.Fatal(err)
-        }
-
-        group, err := user.LookupGroupId(fmt.Sprintf("%d", info.Sys().(*syscall.Stat_t).Gid))
-        if err != nil {
-            log.Fatal(err)
-        }
-
-        groupStr := brightBlue + group.Name + reset
-        icon := getFileIcon(file, info.Mode(), directory)
+    for _, entry := range l.Entries {
+        groupStr := brightBlue + groupName(entry.Info) + reset
 
         line := fmt.Sprintf(
-            "%-*s %s %s",
-            maxLen["group"], groupStr,
-            icon,
-            file.Name(),
+            "%-*s %s",
+            groupWidth, groupStr,
+            ls.withIcon(entry.Icon, ls.styledName(entry.file, entry.Dir, entry.Rel)),
         )
 
-        fmt.Println(line)
+        fmt.Fprintln(w, line)
     }
 
-    if showSummary {
-        fmt.Println()
-        printSummary(filteredFiles, directory)
+    writeSummary(w, l)
+    return nil
+}
+
+// longColumns are the fields of one line of the long listing, before
+// padding and colours.
+type longColumns struct {
+    permissions, size, owner, group, month, day, time string
+}
+
+func (ls *lister) newLongColumns(entry Entry, humanReadable bool) longColumns {
+    modTime := entry.Info.ModTime()
+    return longColumns{
+        permissions: ls.formatPermissions(entry.file, entry.Info.Mode(), entry.Dir),
+        size:        formatSize(ls.entrySize(entry), humanReadable),
+        owner:       ownerName(entry.Info),
+        group:       groupName(entry.Info),
+        month:       modTime.Format("Jan"),
+        day:         fmt.Sprintf("%2d", modTime.Day()),
+        time:        modTime.Format("15:04:05 2006"),
     }
 }
 
-func printLongListing(files []os.DirEntry, directory string, humanReadable bool) {
+// longPrefixes returns the columns the long listing shows in front of
+// the git status and name of each entry, padded to line up across
+// entries.
+func (ls *lister) longPrefixes(entries []Entry, humanReadable bool) []string {
     maxLen := map[string]int{
         "permissions": 0,
         "size":        0,
@@ -780,82 +564,57 @@ func printLongListing(files []os.DirEntry, directory string, humanReadable bool)
         "month":       0,
         "day":         0,
         "time":        0,
-        "linkTarget":  0,
+        "author":      0,
     }
 
-    var filteredFiles []os.DirEntry
-    for _, file := range files {
-        info, err := file.Info()
-        if err != nil {
-            log.Fatal(err)
-        }
+    if ls.opts.Checksum != "" {
+        ls.loadChecksums(entries)
+    }
 
-        permissions := formatPermissions(file, info.Mode(), directory)
-        size := info.Size()
-        sizeStr := formatSize(size, humanReadable)
-        owner, err := user.LookupId(fmt.Sprintf("%d", info.Sys().(*syscall.Stat_t).Uid))
-        if err != nil {
-            log.Fatal(err)
-        }
-        group, err := user.LookupGroupId(fmt.Sprintf("%d", info.Sys().(*syscall.Stat_t).Gid))
-        if err != nil {
-            log.Fatal(err)
-        }
-        modTime := info.ModTime()
-        month := modTime.Format("Jan")
-        day := fmt.Sprintf("%2d", modTime.Day())
-        timeStr := modTime.Format("15:04:05 2006")
-
-        maxLen["permissions"] = max(maxLen["permissions"], len(permissions))
-        maxLen["size"] = max(maxLen["size"], len(sizeStr))
-        maxLen["owner"] = max(maxLen["owner"], len(owner.Username))
-        maxLen["group"] = max(maxLen["group"], len(group.Name))
-        maxLen["month"] = max(maxLen["month"], len(month))
-        maxLen["day"] = max(maxLen["day"], len(day))
-        maxLen["time"] = max(maxLen["time"], len(timeStr))
-
-        if file.Type()&os.ModeSymlink != 0 {
-            linkTarget, err := os.Readlink(filepath.Join(directory, file.Name()))
-            if err == nil {
-                maxLen["linkTarget"] = max(maxLen["linkTarget"], len(linkTarget)+5)
+    kindWidth := ls.kindWidth(entries)
+    scale := ls.newBarScale(entries)
+
+    columns := make([]longColumns, len(entries))
+    for i, entry := range entries {
+        c := ls.newLongColumns(entry, humanReadable)
+        columns[i] = c
+
+        maxLen["permissions"] = max(maxLen["permissions"], len(c.permissions))
+        maxLen["size"] = max(maxLen["size"], len(c.size))
+        maxLen["owner"] = max(maxLen["owner"], len(c.owner))
+        maxLen["group"] = max(maxLen["group"], len(c.group))
+        maxLen["month"] = max(maxLen["month"], len(c.month))
+        maxLen["day"] = max(maxLen["day"], len(c.day))
+        maxLen["time"] = max(maxLen["time"], len(c.time))
+
+        if ls.opts.GitLog {
+            if commit := ls.gitLastCommit(entry.Dir, entry.Name); commit != nil {
+                maxLen["author"] = max(maxLen["author"], len(commit.author))
             }
         }
-
-        filteredFiles = append(filteredFiles, file)
     }
 
-    for _, file := range filteredFiles {
-        info, err := file.Info()
-        if err != nil {
-            log.Fatal(err)
-        }
+    prefixes := make([]string, len(entries))
+    for i, entry := range entries {
+        c := columns[i]
 
-        permissions := formatPermissions(file, info.Mode(), directory)
-        size := info.Size()
-        sizeStr := formatSize(size, humanReadable)
-        owner, err := user.LookupId(fmt.Sprintf("%d", info.Sys().(*syscall.Stat_t).Uid))
-        if err != nil {
-            log.Fatal(err)
+        permissions := green + c.permissions + reset
+        sizeStr := fmt.Sprintf("%*s", maxLen["size"], c.size)
+        if ls.opts.Bars {
+            sizeStr += " " + scale.bar(ls.entrySize(entry), entry.IsDir())
         }
-        group, err := user.LookupGroupId(fmt.Sprintf("%d", info.Sys().(*syscall.Stat_t).Gid))
-        if err != nil {
-            log.Fatal(err)
-        }
-        modTime := info.ModTime()
-        month := modTime.Format("Jan")
-        day := fmt.Sprintf("%2d", modTime.Day())
-        timeStr := modTime.Format("15:04:05 2006")
+        ownerStr := cyan + c.owner + reset
+        groupStr := brightBlue + c.group + reset
+        monthStr := magenta + c.month + reset
+        dayStr := magenta + c.day + reset
+        timeStr := magenta + c.time + reset
 
-        permissions = green + permissions + reset
-        sizeStr = fmt.Sprintf("%*s", maxLen["size"], sizeStr)
-        ownerStr := cyan + owner.Username + reset
-        groupStr := brightBlue + group.Name + reset
-        monthStr := magenta + month + reset
-        dayStr := magenta + day + reset
-        timeStr = magenta + timeStr + reset
+        if ls.opts.GitLog {
+            timeStr += " " + formatGitLog(ls.gitLastCommit(entry.Dir, entry.Name), maxLen["author"])
+        }
 
-        line := fmt.Sprintf(
-            "%-*s  %s  %-*s  %-*s %-*s %-*s %-*s %s %s",
+        prefixes[i] = fmt.Sprintf(
+            "%-*s  %s  %-*s  %-*s %-*s %-*s %-*s %s%s",
             maxLen["permissions"], permissions,
             sizeStr,
             maxLen["owner"], ownerStr,
@@ -863,23 +622,36 @@ func printLongListing(files []os.DirEntry, directory string, humanReadable bool)
             maxLen["month"], monthStr,
             maxLen["day"], dayStr,
             maxLen["time"], timeStr,
-            getFileIcon(file, info.Mode(), directory), file.Name(),
+            ls.checksumColumn(entry.Dir, entry.Name),
+            ls.kindColumn(entry.file, entry.Dir, kindWidth),
         )
+    }
+    return prefixes
+}
 
-        if file.Type()&os.ModeSymlink != 0 {
-            linkTarget, err := os.Readlink(filepath.Join(directory, file.Name()))
-            if err == nil {
-                line += fmt.Sprintf(" %s==> %s%s", cyan, linkTarget, reset)
-            }
+func renderLong(w io.Writer, l *Listing) error {
+    ls := l.lister()
+    prefixes := ls.longPrefixes(l.Entries, l.Options.HumanReadable)
+
+    for i, entry := range l.Entries {
+        line := prefixes[i] +
+            ls.gitStatusColumn(entry.Dir, entry.Name, entry.IsDir()) +
+            ls.withIcon(entry.Icon, ls.styledName(entry.file, entry.Dir, entry.Rel))
+
+        if entry.IsDir() {
+            branch, _ := ls.gitBranchLabel(entry.Dir, entry.Name)
+            line += branch
         }
 
-        fmt.Println(line)
-    }
+        if entry.Info.Mode()&os.ModeSymlink != 0 && entry.LinkTarget != "" {
+            line += fmt.Sprintf(" %s==> %s%s", cyan, entry.LinkTarget, reset)
+        }
 
-    if showSummary {
-        fmt.Println()
-        printSummary(filteredFiles, directory)
+        fmt.Fprintln(w, line)
     }
+
+    writeSummary(w, l)
+    return nil
 }
 
 func max(a, b int) int {
@@ -921,17 +693,17 @@ func colorize(char byte) string {
     }
 }
 
-func formatPermissions(file os.DirEntry, mode os.FileMode, directory string) string {
+func (ls *lister) formatPermissions(file os.DirEntry, mode os.FileMode, directory string) string {
     perms := make([]byte, 10)
     for i := range perms {
         perms[i] = '-'
     }
 
     if file.Type()&os.ModeSymlink != 0 {
-        linkTarget, err := os.Readlink(filepath.Join(directory, file.Name()))
+        linkTarget, err := ls.readLink(filepath.Join(directory, file.Name()))
         if err == nil {
             symlinkTarget := filepath.Join(directory, linkTarget)
-            targetInfo, err := os.Stat(symlinkTarget)
+            targetInfo, err := ls.statPath(symlinkTarget)
             if err == nil && targetInfo.IsDir() {
                 perms[0] = 'l'
                 perms[1] = 'd'
@@ -994,225 +766,8 @@ func rwx(perm os.FileMode) string {
     return b.String()
 }
 
-func printEntry(file os.DirEntry, mode os.FileMode, directory string) {
-    perms := formatPermissions(file, mode, directory)
-    name := file.Name()
-    fmt.Printf("%s %s\n", perms, name)
-}
-
-func getDirectoryIcon(directory string) string {
-    for dirType, icon := range directoryIcons {
-        if filepath.Base(directory) == dirType {
-            return icon
-        }
-    }
-    return directoryIcons["default"]
-}
-
-func getSpecialFileIcon(fileName string) (string, bool) {
-    icon, found := specialFileIcons[fileName]
-    return icon, found
-}
-
-func getFileIcon(file os.DirEntry, mode os.FileMode, directory string) string {
-    if file.Type()&os.ModeSymlink != 0 {
-        linkTarget, err := os.Readlink(filepath.Join(directory, file.Name()))
-        if err == nil {
-            symlinkTarget := filepath.Join(directory, linkTarget)
-            targetInfo, err := os.Stat(symlinkTarget)
-            if err == nil && targetInfo.IsDir() {
-                return iconSymlinkDir
-            } else {
-                return iconSymlinkFile
-            }
-        }
-    }
-
-    if mode.IsDir() {
-        icon := getDirectoryIcon(file.Name())
-        return blue + icon + " " + reset
-    }
-
-    if icon, found := getSpecialFileIcon(file.Name()); found {
-        return icon
-    }
-
-    ext := filepath.Ext(file.Name())
-    icon, exists := fileIcons[ext]
-    if exists {
-        switch ext {
-        case ".sh", ".ps1":
-            if mode&os.ModePerm&0111 != 0 {
-                return brightGreen + icon + reset
-            } else {
-                return white + icon + reset
-            }
-        case ".cpp", ".hpp", ".cxx", ".hxx", ".dart", ".gd", ".v":
-            return blue + icon + reset
-        case ".css", ".ml", ".rst", ".nix":
-            return lightBlue + icon + reset
-        case ".c", ".h", ".mp3", ".m4a", ".ogg", ".flac", ".php", ".lua", ".sql", ".m":
-            return brightBlue + icon + reset
-        case ".png", ".jpg", ".jpeg", ".JPG", ".webp", ".R", ".ts", ".bmp":
-            return darkBlue + icon + reset
-        case ".md", ".epub", ".obj", ".go":
-            return cyan + icon + reset
-        case ".xml":
-            return lightCyan + icon + reset
-        case ".exe", ".desktop", ".mk":
-            return brightCyan + icon + reset
-        case ".gif", ".xcf", ".el", ".lisp":
-            return magenta + icon + reset
-        case ".cs", ".mp4", ".mkv", ".webm", ".org", ".ejs":
-            return darkMagenta + icon + reset
-        case ".js", ".lock":
-            return yellow + icon + reset
-        case ".json", ".tiff", ".nim":
-            return brightYellow + icon + reset
-        case ".patch", ".diff", ".py":
-            return darkYellow + icon + reset
-        case ".yml", ".yaml", ".pdf", ".db":
-            return brightRed + icon + reset
-        case ".deb":
-            return lightRed + icon + reset
-        case ".rb", ".cmake", ".pl", ".scala", ".erl", ".build":
-            return red + icon + reset
-        case ".htm", ".html", ".java", ".jar", ".git", ".ps", ".eps", ".swift":
-            return orange + icon + reset
-        case ".toml", ".zig":
-            return darkOrange + icon + reset
-        case ".tmux.conf":
-            return green + icon + reset
-        case ".xbps", ".vim", ".jai":
-            return darkGreen + icon + reset
-        case ".iso", ".asm", ".f90", ".groovy", ".ini", ".cfg":
-            return gray + icon + reset
-        case ".conf", ".bat", ".rs":
-            return darkGray + icon + reset
-        case ".fish", ".o", ".m4":
-            return lightGray + icon + reset
-        case ".1", ".hs":
-            return lightBrown + icon + reset
-        case ".txt", ".app":
-            return white + icon + reset
-        case ".zip", ".tar", ".gz", ".bz2", ".xz", ".7z", ".svg", ".kt", ".ex", ".zst":
-            return lightPurple + icon + reset
-        default:
-            return icon
-        }
-    }
-
-    if mode&os.ModePerm&0111 != 0 {
-        return green + " " + reset
-    }
-
-    return " " + reset
-}
-
 func getFileNameAndExtension(file os.DirEntry) (string, string) {
     ext := filepath.Ext(file.Name())
     name := strings.TrimSuffix(file.Name(), ext)
     return name, ext
 }
-
-func printSummary(files []os.DirEntry, directory string) {
-    fileCount, dirCount, symlinkFileCount, symlinkDirCount := 0, 0, 0, 0
-
-    for _, file := range files {
-        if file.Type()&os.ModeSymlink != 0 {
-            linkTarget, err := os.Readlink(filepath.Join(directory, file.Name()))
-            if err == nil {
-                targetInfo, err := os.Stat(filepath.Join(directory, linkTarget))
-                if err == nil && targetInfo.IsDir() {
-                    symlinkDirCount++
-                } else {
-                    symlinkFileCount++
-                }
-            }
-        } else if file.IsDir() {
-            dirCount++
-        } else {
-            fileCount++
-        }
-    }
-
-    fmt.Printf(iconDirectory + " Directories: %s%d%s\n", blue, dirCount, reset)
-    fmt.Printf(iconOther + " Files: %s%d%s\n", red, fileCount, reset)
-
-    if symlinkDirCount > 0 {
-        fmt.Printf(iconSymlinkDir + " Symlinked Directories: %s%d%s\n", magenta, symlinkDirCount, reset)
-    }
-    if symlinkFileCount > 0 {
-        fmt.Printf(iconSymlinkFile + " Symlinked Files: %s%d%s\n", cyan, symlinkFileCount, reset)
-    }
-
-    total := dirCount + fileCount + symlinkDirCount + symlinkFileCount
-    fmt.Printf( "TOTAL" + ":%s%d%s\n", brightGreen, total, reset)
-}
-
-func printTree(path, prefix string, isLast bool, currentDepth, maxDepth int) (totalFiles, totalDirs int) {
-    if maxDepth != -1 && currentDepth > maxDepth {
-        return 0, 0
-    }
-
-    files, err := os.ReadDir(path)
-    if err != nil {
-        if os.IsPermission(err) {
-            fmt.Printf("%sError: Permission denied for %s%s\n", red, path, reset)
-        } else {
-            fmt.Printf("%sError reading directory %s: %v%s\n", red, path, err, reset)
-        }
-        return 0, 0
-    }
-
-    var filteredFiles []os.DirEntry
-    for _, file := range files {
-        if showHidden || !strings.HasPrefix(file.Name(), ".") {
-            filteredFiles = append(filteredFiles, file)
-        }
-    }
-
-    for i, file := range filteredFiles {
-        isLastFile := i == len(filteredFiles)-1
-        if isLastFile {
-            fmt.Printf("%s└── ", prefix)
-        } else {
-            fmt.Printf("%s├── ", prefix)
-        }
-
-        maxFileNameLength := getMaxNameLength(filteredFiles)
-        printFile(file, path, maxFileNameLength, true)
-        fmt.Println()
-
-        if file.Type()&os.ModeSymlink != 0 {
-            linkTarget, err := os.Readlink(filepath.Join(path, file.Name()))
-            if err == nil {
-                fmt.Printf("%s%s ==> %s%s\n", prefix, cyan, linkTarget, reset)
-            } else {
-                fmt.Printf("%s%s %s%s\n", prefix, red, "==> error", reset)
-            }
-        }
-
-        if file.IsDir() {
-            newPrefix := prefix
-            if isLastFile {
-                newPrefix += "    "
-            } else {
-                newPrefix += "│   "
-            }
-            subPath := filepath.Join(path, file.Name())
-            subFiles, subDirs := printTree(subPath, newPrefix, isLastFile, currentDepth+1, maxDepth)
-            totalFiles += subFiles
-            totalDirs += subDirs + 1
-        } else {
-            totalFiles++
-        }
-    }
-
-    if currentDepth == 0 && showSummary {
-        fmt.Println()
-        printSummary(filteredFiles, path)
-    }
-
-    return totalFiles, totalDirs
-}
@@ -0,0 +1,371 @@
+package gols
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Entry is a file, directory or symlink found by List or Walk.
+type Entry struct {
+	Name  string      // base name
+	Dir   string      // directory the entry is in
+	Path  string      // Dir joined with Name
+	Rel   string      // slash-separated path relative to the listed root
+	Depth int         // 0 for the entries of the root itself
+	Info  fs.FileInfo // the entry itself, not what a symlink points to
+
+	// LinkTarget is the target of a symlink as stored in the link.
+	LinkTarget string
+
+	// Icon is the coloured icon gols shows in front of the name.
+	Icon string
+
+	// Err is set on a directory of a recursive listing whose contents
+	// could not be read.
+	Err error
+
+	file    os.DirEntry
+	stats   dirStats
+	descend bool // a directory, or a symlink to one with -L, the walk goes into
+	entered bool // read by the walk, which is within the depth limit
+	loop    bool // a symlink back into a directory it is in, not followed
+}
+
+// dirStats is what walkDir learns about a directory when it reads
+// it, for the tree view to show next to its name.
+type dirStats struct {
+	hidden   int // hidden entries left out
+	unlisted int // entries of a directory over the file limit, which is not entered
+}
+
+// newDirStats counts the files of a directory below the root of a tree.
+func (ls *lister) newDirStats(files []os.DirEntry) dirStats {
+	var stats dirStats
+	if !ls.showHidden {
+		for _, file := range files {
+			if strings.HasPrefix(file.Name(), ".") {
+				stats.hidden++
+			}
+		}
+	}
+	if listed := len(files) - stats.hidden; ls.opts.FileLimit > 0 && listed > ls.opts.FileLimit {
+		stats.unlisted = listed
+	}
+	return stats
+}
+
+// IsDir reports whether the entry is a directory. Symlinks to
+// directories are not.
+func (e Entry) IsDir() bool {
+	return e.Info.IsDir()
+}
+
+func (ls *lister) newEntry(file os.DirEntry, dir, rel string, depth int) (Entry, error) {
+	info, err := file.Info()
+	if err != nil {
+		return Entry{}, err
+	}
+	entry := Entry{
+		Name:  file.Name(),
+		Dir:   dir,
+		Path:  filepath.Join(dir, file.Name()),
+		Rel:   rel,
+		Depth: depth,
+		Info:  info,
+		Icon:  ls.getFileIcon(file, info.Mode(), dir),
+		file:  file,
+	}
+	if file.Type()&fs.ModeSymlink != 0 {
+		entry.LinkTarget, _ = ls.readLink(entry.Path)
+	}
+	return entry, nil
+}
+
+// List returns what gols would list for path with opts: the filtered
+// and sorted entries of a directory, or the file itself when path is
+// not one. With opts.Recursive it returns the whole tree in the order
+// of Walk instead, and with opts.RecursiveFlat the entries of every
+// directory in turn, as -R lists them. Paths inside zip and tar
+// archives can be listed like directories.
+func List(ctx context.Context, path string, opts Options) ([]Entry, error) {
+	ls, err := newLister(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.RecursiveFlat {
+		return ls.collectSections(ctx, path)
+	}
+	l, err := ls.newListing(ctx, path)
+	if err == errNoFiles {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return l.Entries, nil
+}
+
+// Walk calls fn for every entry of the tree under root that the tree
+// view of gols -r would show with opts, parents before their children
+// and siblings by name. A directory that does not match the filters
+// itself is still visited when something below it does.
+//
+// As with filepath.WalkDir, fn can return fs.SkipDir to skip a
+// directory, or the rest of the directory a file is in, and fs.SkipAll
+// to stop. Any other error stops the walk and is returned, as are
+// errors reading a directory and the error of ctx once it is done.
+func Walk(ctx context.Context, root string, opts Options, fn func(Entry) error) error {
+	ls, err := newLister(opts)
+	if err != nil {
+		return err
+	}
+
+	if err := ls.openArchive(root); err != nil {
+		return err
+	}
+	err = ls.walkEntries(ctx, root, func(entry Entry) error {
+		if err := fn(entry); err != nil {
+			return err
+		}
+		return entry.Err
+	})
+	if err == fs.SkipDir || err == fs.SkipAll {
+		return nil
+	}
+	return err
+}
+
+// walkEntries calls fn for the entries of the tree under root, in the
+// order of Walk. Only an error reading root itself is returned; the
+// directories below carry theirs in Entry.Err.
+func (ls *lister) walkEntries(ctx context.Context, root string, fn func(Entry) error) error {
+	files, err := ls.readDir(root)
+	if err != nil {
+		return err
+	}
+	ls.treeRoot = root
+	return ls.walkDir(ctx, root, files, 0, ls.rootAncestors(root), fn)
+}
+
+// walkDir visits files, the entries of dir at depth, and what is below
+// them. A directory is read before fn gets it, so the entry carries
+// what was counted in it or the error that kept it from being read. A
+// directory over the file limit is not entered.
+//
+// A directory that does not match the name filters and predicates
+// itself is walked with its entries held back, and passed on with them
+// only when one was kept, so every directory is read once however deep
+// the matches are. ancestors are the device and inode of dir and the
+// directories above it, for descendInto.
+func (ls *lister) walkDir(ctx context.Context, dir string, files []os.DirEntry, depth int, ancestors [][2]uint64, fn func(Entry) error) error {
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel := ls.treeRelPath(dir, file.Name())
+		if !ls.treeVisible(file, dir, rel) {
+			continue
+		}
+		matches := ls.treeMatches(file, dir, rel)
+		if !matches && !file.IsDir() && file.Type()&fs.ModeSymlink == 0 {
+			continue
+		}
+		entry, err := ls.newEntry(file, dir, rel, depth)
+		if err != nil {
+			continue
+		}
+		descend, loop, below := ls.descendInto(entry, ancestors)
+		entry.descend, entry.loop = descend, loop
+
+		var children []os.DirEntry
+		if descend && (ls.opts.MaxDepth == -1 || depth < ls.opts.MaxDepth) {
+			entry.entered = true
+			if ls.group != nil {
+				ls.group.tokens <- struct{}{}
+			}
+			children, entry.Err = ls.readDir(entry.Path)
+			if ls.group != nil {
+				<-ls.group.tokens
+			}
+			if entry.Err == nil {
+				entry.stats = ls.newDirStats(children)
+			}
+		}
+
+		if !matches {
+			if len(children) == 0 {
+				continue
+			}
+			var kept []Entry
+			err := ls.walkDir(ctx, entry.Path, children, depth+1, below, func(e Entry) error {
+				kept = append(kept, e)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if len(kept) == 0 {
+				continue
+			}
+			if entry.stats.unlisted > 0 {
+				// Kept for what is below it, which is still not listed.
+				kept = nil
+			}
+			if err := replay(append([]Entry{entry}, kept...), fn); err != nil {
+				return err
+			}
+			continue
+		}
+
+		err = fn(entry)
+		if err == fs.SkipDir {
+			if entry.IsDir() || entry.descend {
+				continue
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if entry.stats.unlisted > 0 {
+			continue
+		}
+		if ls.group != nil {
+			ls.group.spawn(func() error {
+				return ls.walkDir(ctx, entry.Path, children, depth+1, below, fn)
+			})
+			continue
+		}
+		if err := ls.walkDir(ctx, entry.Path, children, depth+1, below, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkGroup runs the walk of each directory in a goroutine of its own,
+// for walkParallel.
+type walkGroup struct {
+	wg     sync.WaitGroup
+	tokens chan struct{} // held while reading a directory
+	cancel context.CancelFunc
+	once   sync.Once
+	err    error
+}
+
+func (g *walkGroup) spawn(walk func() error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := walk(); err != nil {
+			g.once.Do(func() {
+				g.err = err
+				g.cancel()
+			})
+		}
+	}()
+}
+
+// walkParallel is walkEntries with a goroutine for each directory and at
+// most one directory read per CPU at a time. fn is called concurrently,
+// in no set order, and the first error it returns stops the walk. As
+// directories cannot be held back in parallel, ls must not prune: see
+// withoutIncludes.
+func (ls *lister) walkParallel(ctx context.Context, root string, fn func(Entry) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	p := *ls
+	p.group = &walkGroup{tokens: make(chan struct{}, runtime.NumCPU()), cancel: cancel}
+	p.group.spawn(func() error {
+		return p.walkEntries(ctx, root, fn)
+	})
+	p.group.wg.Wait()
+	return p.group.err
+}
+
+// replay passes a directory held back by walkDir and the entries below
+// it to fn, with fs.SkipDir skipping what it skips in the walk.
+func replay(entries []Entry, fn func(Entry) error) error {
+	skip := -1
+	for _, entry := range entries {
+		if skip != -1 && entry.Depth >= skip {
+			continue
+		}
+		skip = -1
+
+		err := fn(entry)
+		switch {
+		case err == fs.SkipDir && (entry.IsDir() || entry.descend):
+			skip = entry.Depth + 1
+		case err == fs.SkipDir:
+			skip = entry.Depth
+		case err != nil:
+			return err
+		}
+	}
+	return nil
+}
+
+// collectTree returns the entries of the tree under root. Directories
+// that cannot be read keep the error in Entry.Err for the tree view to
+// show in place, and what was counted in the others.
+func (ls *lister) collectTree(ctx context.Context, root string) ([]Entry, error) {
+	var entries []Entry
+	err := ls.walkEntries(ctx, root, func(entry Entry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	return entries, err
+}
+
+// newListing reads what the options select under path into the Listing
+// handed to renderers.
+func (ls *lister) newListing(ctx context.Context, path string) (*Listing, error) {
+	files, dir, hidden, err := ls.readEntries(path)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Listing{Root: path, Dir: dir, Options: ls.opts, ls: ls, hidden: hidden}
+	if ls.recursive {
+		l.Tree = true
+		l.Entries, err = ls.collectTree(ctx, dir)
+		for _, entry := range l.Entries {
+			l.hidden += entry.stats.hidden
+		}
+		return l, err
+	}
+
+	l.Entries = make([]Entry, 0, len(files))
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		entry, err := ls.newEntry(file, dir, file.Name(), 0)
+		if err != nil {
+			continue
+		}
+		l.Entries = append(l.Entries, entry)
+	}
+	return l, nil
+}
+
+// Print writes the listing of path to w the way the gols command does
+// with opts. It reports false when nothing was left to list, after
+// writing "No files found.".
+func Print(w io.Writer, path string, opts Options) (bool, error) {
+	ls, err := newLister(opts)
+	if err != nil {
+		return false, err
+	}
+	return ls.listDirectory(w, path)
+}
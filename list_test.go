@@ -0,0 +1,231 @@
+package gols
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// listFS is the tree the List and Walk tests look at.
+var listFS = fstest.MapFS{
+	"README.md":            {Data: []byte("# gols\n")},
+	"go.mod":               {Data: []byte("module x\n")},
+	".env":                 {Data: []byte("A=1\n")},
+	"cmd/gols/main.go":     {Data: []byte("package main\n")},
+	"src/lib.go":           {Data: []byte("package lib\n")},
+	"src/lib_test.go":      {Data: []byte("package lib\n")},
+	"src/.cache/x.go":      {Data: []byte("package x\n")},
+	"src/util/strings.go":  {Data: []byte("package util\n")},
+	"src/util/strings.txt": {Data: []byte("strings\n")},
+	"docs/guide.md":        {Data: []byte("# guide\n")},
+}
+
+// relPaths joins the Rel of entries with spaces.
+func relPaths(entries []Entry) string {
+	var paths []string
+	for _, entry := range entries {
+		paths = append(paths, entry.Rel)
+	}
+	return strings.Join(paths, " ")
+}
+
+func TestList(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		opts func(*Options)
+		want string
+	}{
+		{name: "directory", path: ".", want: "README.md cmd docs go.mod src"},
+		{name: "subdirectory", path: "src", want: "lib.go lib_test.go util"},
+		{name: "file", path: "go.mod", want: "go.mod"},
+		{name: "hidden", path: ".", opts: func(o *Options) { o.All = true }, want: ".env README.md cmd docs go.mod src"},
+		{name: "hidden only", path: ".", opts: func(o *Options) { o.HiddenOnly = true }, want: ".env"},
+		{name: "directories", path: ".", opts: func(o *Options) { o.DirsOnly = true }, want: "cmd docs src"},
+		{name: "files", path: ".", opts: func(o *Options) { o.FilesOnly = true }, want: "README.md go.mod"},
+		{name: "extensions", path: "src", opts: func(o *Options) { o.Extensions = []string{"go"} }, want: "lib.go lib_test.go"},
+		{name: "ignored", path: ".", opts: func(o *Options) { o.Ignore = []string{"*.md", "cmd"} }, want: "docs go.mod src"},
+		{name: "nothing left", path: ".", opts: func(o *Options) { o.Extensions = []string{"rs"} }, want: ""},
+		{
+			name: "tree",
+			path: ".",
+			opts: func(o *Options) { o.Recursive = true },
+			want: "README.md cmd cmd/gols cmd/gols/main.go docs docs/guide.md go.mod src src/lib.go src/lib_test.go src/util src/util/strings.go src/util/strings.txt",
+		},
+		{
+			name: "tree to depth 1",
+			path: ".",
+			opts: func(o *Options) { o.Recursive, o.MaxDepth = true, 1 },
+			want: "README.md cmd cmd/gols docs docs/guide.md go.mod src src/lib.go src/lib_test.go src/util",
+		},
+		{
+			name: "tree leading to matches",
+			path: ".",
+			opts: func(o *Options) { o.Recursive, o.Match = true, []string{"strings.*"} },
+			want: "src src/util src/util/strings.go src/util/strings.txt",
+		},
+		{
+			name: "tree of files under a limit",
+			path: ".",
+			opts: func(o *Options) { o.Recursive, o.FileLimit = true, 2 },
+			want: "README.md cmd cmd/gols cmd/gols/main.go docs docs/guide.md go.mod src",
+		},
+		{
+			name: "sections",
+			path: "src",
+			opts: func(o *Options) { o.RecursiveFlat = true },
+			want: "lib.go lib_test.go util util/strings.go util/strings.txt",
+		},
+	}
+	for _, test := range tests {
+		opts := DefaultOptions()
+		opts.FS = listFS
+		if test.opts != nil {
+			test.opts(&opts)
+		}
+		entries, err := List(context.Background(), test.path, opts)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if got := relPaths(entries); got != test.want {
+			t.Errorf("%s: List =\n%s\nwant\n%s", test.name, got, test.want)
+		}
+	}
+
+	opts := DefaultOptions()
+	opts.FS = listFS
+	if _, err := List(context.Background(), "missing", opts); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("List of a missing path: %v", err)
+	}
+}
+
+func TestListEntries(t *testing.T) {
+	opts := DefaultOptions()
+	opts.FS = listFS
+	opts.Recursive = true
+	entries, err := List(context.Background(), "src", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		wantDepth := strings.Count(entry.Rel, "/")
+		if entry.Depth != wantDepth || entry.Path != "src/"+entry.Rel || entry.Info == nil || entry.Info.Name() != entry.Name {
+			t.Errorf("entry %+v", entry)
+		}
+	}
+}
+
+func TestWalk(t *testing.T) {
+	errStop := errors.New("stop")
+	tests := []struct {
+		name string
+		fn   func(Entry) error
+		want string
+		err  error
+	}{
+		{
+			name: "everything",
+			fn:   func(Entry) error { return nil },
+			want: "README.md cmd cmd/gols cmd/gols/main.go docs docs/guide.md go.mod src src/lib.go src/lib_test.go src/util src/util/strings.go src/util/strings.txt",
+		},
+		{
+			name: "skip a directory",
+			fn: func(e Entry) error {
+				if e.Rel == "src" || e.Rel == "cmd/gols" {
+					return fs.SkipDir
+				}
+				return nil
+			},
+			want: "README.md cmd cmd/gols docs docs/guide.md go.mod src",
+		},
+		{
+			name: "skip the rest of a directory",
+			fn: func(e Entry) error {
+				if e.Rel == "src/lib.go" {
+					return fs.SkipDir
+				}
+				return nil
+			},
+			want: "README.md cmd cmd/gols cmd/gols/main.go docs docs/guide.md go.mod src src/lib.go",
+		},
+		{
+			name: "skip all",
+			fn: func(e Entry) error {
+				if e.Rel == "docs" {
+					return fs.SkipAll
+				}
+				return nil
+			},
+			want: "README.md cmd cmd/gols cmd/gols/main.go docs",
+		},
+		{
+			name: "stop",
+			fn: func(e Entry) error {
+				if e.Rel == "cmd/gols" {
+					return errStop
+				}
+				return nil
+			},
+			want: "README.md cmd cmd/gols",
+			err:  errStop,
+		},
+	}
+	for _, test := range tests {
+		opts := DefaultOptions()
+		opts.FS = listFS
+		var visited []Entry
+		err := Walk(context.Background(), ".", opts, func(e Entry) error {
+			visited = append(visited, e)
+			return test.fn(e)
+		})
+		if err != test.err {
+			t.Errorf("%s: Walk = %v, want %v", test.name, err, test.err)
+		}
+		if got := relPaths(visited); got != test.want {
+			t.Errorf("%s: visited\n%s\nwant\n%s", test.name, got, test.want)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	opts := DefaultOptions()
+	opts.FS = listFS
+	if err := Walk(ctx, ".", opts, func(Entry) error { return nil }); err != context.Canceled {
+		t.Errorf("Walk with a cancelled context = %v", err)
+	}
+}
+
+func TestWalkFiltered(t *testing.T) {
+	tests := []struct {
+		name string
+		skip string
+		want string
+	}{
+		{name: "leading to matches", want: "src src/util src/util/strings.go src/util/strings.txt"},
+		{name: "skip a held back directory", skip: "src", want: "src"},
+		{name: "skip below a held back directory", skip: "src/util", want: "src src/util"},
+		{name: "skip the rest of a held back directory", skip: "src/util/strings.go", want: "src src/util src/util/strings.go"},
+	}
+	for _, test := range tests {
+		opts := DefaultOptions()
+		opts.FS = listFS
+		opts.Match = []string{"strings.*"}
+		var visited []Entry
+		err := Walk(context.Background(), ".", opts, func(e Entry) error {
+			visited = append(visited, e)
+			if e.Rel == test.skip {
+				return fs.SkipDir
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if got := relPaths(visited); got != test.want {
+			t.Errorf("%s: visited\n%s\nwant\n%s", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package gols
+
+import (
+	"io/fs"
+	"sync"
+	"time"
+)
+
+// lister carries out one call of the package: the options it was given,
+// what follows from them, and what it read so far. Nothing is shared
+// between calls, so they can run at the same time.
+type lister struct {
+	opts Options
+
+	showHidden bool   // -a or -A
+	recursive  bool   // the tree view, with -r or --format=tree
+	treeStyle  string // --tree-style, "unicode" when not given
+	sniff      bool   // --sniff or --kind
+	matcher    nameMatcher
+
+	// fsys is Options.FS, or nil for the OS file system. Every read of
+	// the listing goes through the methods in fs.go and archive.go,
+	// which keep taking paths built with filepath.
+	fsys fs.FS
+
+	// treeRoot is the directory a tree or -R listing started from;
+	// entries are matched by their path relative to it.
+	treeRoot string
+
+	// watchChanged is set while Watch runs and maps the path of each
+	// recently changed entry to the end of its highlight.
+	watchChanged map[string]time.Time
+
+	// group is set on the copy of a lister that walkParallel walks with.
+	group *walkGroup
+
+	cache *listCache
+}
+
+// listCache holds what a lister reads once and looks up many times. The
+// copies of a lister that walk with other filters share it. The mutexes
+// guard the caches that the concurrent walk of --find reaches.
+type listCache struct {
+	dirSizes     map[string]int64
+	checksums    map[string]string
+	gitRepos     map[string]*gitRepo
+	gitHistories map[string]*gitHistory
+
+	sniffMu sync.Mutex
+	sniffed map[string]fileKind
+
+	archivesMu sync.RWMutex
+	archives   map[string]*archive
+
+	gitignoreMu       sync.Mutex
+	gitignoreMatchers map[string]*gitignoreMatcher
+	gitignoreRoots    map[string]string
+}
+
+func newListCache() *listCache {
+	return &listCache{
+		dirSizes:          map[string]int64{},
+		checksums:         map[string]string{},
+		gitRepos:          map[string]*gitRepo{},
+		gitHistories:      map[string]*gitHistory{},
+		sniffed:           map[string]fileKind{},
+		archives:          map[string]*archive{},
+		gitignoreMatchers: map[string]*gitignoreMatcher{},
+		gitignoreRoots:    map[string]string{},
+	}
+}
+
+// forget drops everything ls read so far: git state, gitignore rules,
+// archives, sizes and checksums. --watch calls it before each rescan,
+// as any of them may have changed in between.
+func (ls *lister) forget() {
+	ls.cache = newListCache()
+}
+
+// newLister validates o and prepares a call with it.
+func newLister(o Options) (*lister, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+
+	ls := &lister{
+		opts:       o,
+		showHidden: o.All || o.HiddenOnly,
+		recursive:  o.Recursive || o.Format == "tree",
+		treeStyle:  o.TreeStyle,
+		sniff:      o.Sniff || o.Kind,
+		matcher:    o.nameMatcher(),
+		fsys:       o.FS,
+		cache:      newListCache(),
+	}
+	if ls.treeStyle == "" {
+		ls.treeStyle = "unicode"
+	}
+	if err := ls.matcher.compile(); err != nil {
+		return nil, err
+	}
+	return ls, nil
+}
+
+// withoutIncludes returns a copy of ls whose walk enters every
+// directory the tree view could, leaving the name filters, predicates
+// and file limit to its caller. Hidden and ignored entries stay out.
+func (ls *lister) withoutIncludes() *lister {
+	c := *ls
+	c.matcher = ls.matcher.withoutIncludes()
+	c.opts.Predicates = nil
+	c.opts.FileLimit = 0
+	return &c
+}
+
+// unfiltered returns a copy of ls whose walk takes in everything below
+// a directory, as du does: hidden entries included, and no filters or
+// limits. -L and --one-file-system still apply.
+func (ls *lister) unfiltered() *lister {
+	c := *ls.withoutIncludes()
+	c.showHidden = true
+	c.matcher = nameMatcher{}
+	c.opts.Gitignore = ""
+	c.opts.MaxDepth = -1
+	return &c
+}
+
+// defaultLister serves the exported helpers that are called outside a
+// listing, such as FileIcon.
+func defaultLister() *lister {
+	ls, _ := newLister(DefaultOptions())
+	return ls
+}
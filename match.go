@@ -0,0 +1,217 @@
+package gols
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// nameMatcher decides which entries are listed based on their names:
+// the -e and -x extension lists, --match and --regex for inclusion and
+// --ignore for exclusion.
+type nameMatcher struct {
+	extensions   []string
+	excludedExts []string
+	globs        []string
+	regexSources []string
+	ignores      []string
+	ignoreCase   bool
+
+	extSet         map[string]struct{}
+	excludedExtSet map[string]struct{}
+	regexes        []*regexp.Regexp
+}
+
+// compile prepares the matcher once all flags are parsed, so that
+// --ignore-case applies no matter where it appears on the command line.
+func (m *nameMatcher) compile() error {
+	m.extSet = m.foldSet(m.extensions)
+	m.excludedExtSet = m.foldSet(m.excludedExts)
+
+	m.regexes = nil
+	for _, src := range m.regexSources {
+		if m.ignoreCase {
+			src = "(?i)" + src
+		}
+		re, err := regexp.Compile(src)
+		if err != nil {
+			return fmt.Errorf("invalid regex %q: %v", src, err)
+		}
+		m.regexes = append(m.regexes, re)
+	}
+
+	for _, glob := range append(m.globs, m.ignores...) {
+		if _, err := path.Match(strings.ReplaceAll(glob, "**", "*"), ""); err != nil {
+			return fmt.Errorf("invalid pattern %q", glob)
+		}
+	}
+	return nil
+}
+
+func (m *nameMatcher) foldSet(exts []string) map[string]struct{} {
+	if len(exts) == 0 {
+		return nil
+	}
+	set := make(map[string]struct{})
+	for _, ext := range exts {
+		set[m.fold(strings.TrimPrefix(ext, "."))] = struct{}{}
+	}
+	return set
+}
+
+func (m *nameMatcher) fold(s string) string {
+	if m.ignoreCase {
+		return strings.ToLower(s)
+	}
+	return s
+}
+
+// hasIncludes reports whether only some names are wanted, as opposed to
+// everything that is not ignored.
+func (m *nameMatcher) hasIncludes() bool {
+	return len(m.extSet) > 0 || len(m.globs) > 0 || len(m.regexes) > 0
+}
+
+// withoutIncludes returns a copy of m that keeps only -x and --ignore.
+func (m *nameMatcher) withoutIncludes() nameMatcher {
+	c := *m
+	c.extensions, c.extSet = nil, nil
+	c.globs = nil
+	c.regexSources, c.regexes = nil, nil
+	return c
+}
+
+func (m *nameMatcher) active() bool {
+	return m.hasIncludes() || len(m.excludedExtSet) > 0 || len(m.ignores) > 0
+}
+
+// ignored reports whether relPath, the slash-separated path of an entry
+// relative to the listed directory, is excluded by -x or --ignore.
+func (m *nameMatcher) ignored(relPath string) bool {
+	name := path.Base(relPath)
+	if _, found := m.excludedExtSet[m.fold(strings.TrimPrefix(filepath.Ext(name), "."))]; found {
+		return true
+	}
+	for _, glob := range m.ignores {
+		if m.globMatch(glob, relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// included reports whether relPath satisfies the inclusion filters. The
+// extension list must match when given; --match and --regex patterns
+// are alternatives of which at least one must match.
+func (m *nameMatcher) included(relPath string) bool {
+	name := path.Base(relPath)
+	if len(m.extSet) > 0 {
+		if _, found := m.extSet[m.fold(strings.TrimPrefix(filepath.Ext(name), "."))]; !found {
+			return false
+		}
+	}
+
+	if len(m.globs) == 0 && len(m.regexes) == 0 {
+		return true
+	}
+	for _, glob := range m.globs {
+		if m.globMatch(glob, relPath) {
+			return true
+		}
+	}
+	for _, re := range m.regexes {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *nameMatcher) matches(relPath string) bool {
+	return !m.ignored(relPath) && m.included(relPath)
+}
+
+// globMatch matches a shell pattern against an entry. Patterns without a
+// slash only look at the name; patterns with one are matched against the
+// whole relative path, where "**" stands for any number of directories.
+func (m *nameMatcher) globMatch(pattern, relPath string) bool {
+	pattern, relPath = m.fold(pattern), m.fold(relPath)
+
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(relPath))
+		return ok
+	}
+	return matchSegments(strings.Split(strings.TrimPrefix(pattern, "/"), "/"), strings.Split(relPath, "/"))
+}
+
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for len(pattern) > 0 && pattern[0] == "**" {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := range parts {
+				if matchSegments(pattern, parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
+
+// filterNames applies the matcher to the entries of a directory whose
+// path relative to the listing root is relDir.
+func (ls *lister) filterNames(files []os.DirEntry, relDir string) []os.DirEntry {
+	var result []os.DirEntry
+	for _, file := range files {
+		if ls.matcher.matches(path.Join(relDir, file.Name())) {
+			result = append(result, file)
+		}
+	}
+	return result
+}
+
+// treeRelPath returns the slash-separated path of name in dir relative
+// to the root of the tree being listed.
+func (ls *lister) treeRelPath(dir, name string) string {
+	rel, err := filepath.Rel(ls.treeRoot, filepath.Join(dir, name))
+	if err != nil {
+		return name
+	}
+	return filepath.ToSlash(rel)
+}
+
+// treeVisible reports whether the tree walk looks at file, an entry of
+// dir, at all. Hidden and ignored entries are left out together with
+// what is below them.
+func (ls *lister) treeVisible(file os.DirEntry, dir, relPath string) bool {
+	if !ls.showHidden && strings.HasPrefix(file.Name(), ".") {
+		return false
+	}
+	if ls.matcher.ignored(relPath) {
+		return false
+	}
+	return ls.opts.Gitignore != "hide" || !ls.gitIgnored(dir, file.Name(), file.IsDir())
+}
+
+// treeMatches reports whether file matches the name filters and the
+// predicates itself. The tree keeps a directory that does not when
+// something below it does, so it leads to every match.
+func (ls *lister) treeMatches(file os.DirEntry, dir, relPath string) bool {
+	return ls.matcher.included(relPath) && ls.matchesPredicates(file, dir)
+}
@@ -0,0 +1,208 @@
+package gols
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestNameMatcher(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		match   []string
+		nomatch []string
+	}{
+		{
+			name:    "extensions",
+			opts:    Options{Extensions: []string{"go", ".md"}},
+			match:   []string{"main.go", "src/lib.go", "README.md"},
+			nomatch: []string{"main.c", "go", "main.GO"},
+		},
+		{
+			name:    "extensions ignoring case",
+			opts:    Options{Extensions: []string{"go"}, IgnoreCase: true},
+			match:   []string{"main.GO", "main.go"},
+			nomatch: []string{"main.c"},
+		},
+		{
+			name:    "excluded extensions",
+			opts:    Options{ExcludeExtensions: []string{"o", "tmp"}},
+			match:   []string{"main.go", "o", "build/out"},
+			nomatch: []string{"main.o", "a/b/c.tmp"},
+		},
+		{
+			name:    "name glob",
+			opts:    Options{Match: []string{"*_test.go"}},
+			match:   []string{"list_test.go", "a/b/list_test.go"},
+			nomatch: []string{"list.go", "list_test.go.orig"},
+		},
+		{
+			name:    "path glob",
+			opts:    Options{Match: []string{"src/*.go"}},
+			match:   []string{"src/main.go"},
+			nomatch: []string{"main.go", "src/pkg/lib.go", "lib/src/main.go"},
+		},
+		{
+			name:    "double star",
+			opts:    Options{Match: []string{"src/**/*.go"}},
+			match:   []string{"src/main.go", "src/a/b/lib.go"},
+			nomatch: []string{"main.go", "src/a/b/lib.c"},
+		},
+		{
+			name:    "leading double star",
+			opts:    Options{Match: []string{"**/testdata/*"}},
+			match:   []string{"testdata/x", "a/b/testdata/y"},
+			nomatch: []string{"testdata", "a/testdata/b/c"},
+		},
+		{
+			name:    "anchored glob",
+			opts:    Options{Match: []string{"/cmd/*"}},
+			match:   []string{"cmd/gols"},
+			nomatch: []string{"a/cmd/gols"},
+		},
+		{
+			name:    "regex",
+			opts:    Options{Regex: []string{`^[A-Z]+$`}},
+			match:   []string{"LICENSE", "docs/README"},
+			nomatch: []string{"License", "README.md"},
+		},
+		{
+			name:    "regex ignoring case",
+			opts:    Options{Regex: []string{`^license$`}, IgnoreCase: true},
+			match:   []string{"LICENSE"},
+			nomatch: []string{"LICENSE.txt"},
+		},
+		{
+			name:    "globs and regexes are alternatives",
+			opts:    Options{Match: []string{"*.go"}, Regex: []string{`^Makefile$`}},
+			match:   []string{"main.go", "Makefile"},
+			nomatch: []string{"main.c"},
+		},
+		{
+			name:    "extensions and globs both apply",
+			opts:    Options{Extensions: []string{"go"}, Match: []string{"main*"}},
+			match:   []string{"main.go"},
+			nomatch: []string{"lib.go", "main.c"},
+		},
+		{
+			name:    "ignore",
+			opts:    Options{Ignore: []string{"vendor", "*.log"}},
+			match:   []string{"main.go", "vendors"},
+			nomatch: []string{"vendor", "a/vendor", "x.log"},
+		},
+		{
+			name:    "ignore wins",
+			opts:    Options{Match: []string{"*.go"}, Ignore: []string{"gen_*"}},
+			match:   []string{"main.go"},
+			nomatch: []string{"gen_main.go"},
+		},
+		{
+			name:    "ignore ignoring case",
+			opts:    Options{Ignore: []string{"readme*"}, IgnoreCase: true},
+			match:   []string{"LICENSE"},
+			nomatch: []string{"README.md"},
+		},
+	}
+	for _, test := range tests {
+		m := test.opts.nameMatcher()
+		if err := m.compile(); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !m.active() {
+			t.Errorf("%s: matcher is not active", test.name)
+		}
+		for _, rel := range test.match {
+			if !m.matches(rel) {
+				t.Errorf("%s: %s does not match", test.name, rel)
+			}
+		}
+		for _, rel := range test.nomatch {
+			if m.matches(rel) {
+				t.Errorf("%s: %s matches", test.name, rel)
+			}
+		}
+	}
+}
+
+func TestNameMatcherWithoutIncludes(t *testing.T) {
+	m := Options{
+		Extensions:        []string{"go"},
+		ExcludeExtensions: []string{"o"},
+		Match:             []string{"*.go"},
+		Regex:             []string{`^main`},
+		Ignore:            []string{"vendor"},
+	}.nameMatcher()
+	if err := m.compile(); err != nil {
+		t.Fatal(err)
+	}
+
+	c := m.withoutIncludes()
+	if c.hasIncludes() || !c.active() {
+		t.Errorf("withoutIncludes: hasIncludes = %v, active = %v", c.hasIncludes(), c.active())
+	}
+	for rel, want := range map[string]bool{"README.md": true, "main.o": false, "vendor": false} {
+		if got := c.matches(rel); got != want {
+			t.Errorf("withoutIncludes: matches(%q) = %v, want %v", rel, got, want)
+		}
+	}
+	if !m.hasIncludes() || m.matches("README.md") {
+		t.Error("withoutIncludes changed the original matcher")
+	}
+}
+
+func TestNameMatcherInvalid(t *testing.T) {
+	tests := []struct {
+		opts Options
+		err  string
+	}{
+		{Options{Regex: []string{"("}}, "invalid regex"},
+		{Options{Match: []string{"[a-"}}, "invalid pattern"},
+		{Options{Ignore: []string{"x["}}, "invalid pattern"},
+	}
+	for _, test := range tests {
+		m := test.opts.nameMatcher()
+		if err := m.compile(); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("compile of %+v = %v, want %q", test.opts, err, test.err)
+		}
+	}
+	if m := DefaultOptions().nameMatcher(); m.active() {
+		t.Error("a matcher without filters is active")
+	}
+}
+
+func TestTreeFilters(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"src/main.go":         "",
+		"src/gen/gen_main.go": "",
+		"docs/guide.md":       "",
+		".hidden/x.go":        "",
+		"vendor/lib.go":       "",
+	})
+
+	tests := []struct {
+		maxDepth int
+		want     string
+	}{
+		{-1, "src src/main.go"},
+		{0, ""},
+	}
+	for _, test := range tests {
+		opts := DefaultOptions()
+		opts.Match = []string{"*.go"}
+		opts.Ignore = []string{"vendor", "gen_*"}
+		opts.MaxDepth = test.maxDepth
+		var visited []Entry
+		err := Walk(context.Background(), dir, opts, func(e Entry) error {
+			visited = append(visited, e)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := relPaths(visited); got != test.want {
+			t.Errorf("depth %d: walked %q, want %q", test.maxDepth, got, test.want)
+		}
+	}
+}
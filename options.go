@@ -0,0 +1,152 @@
+package gols
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// Options selects what is listed and how, one field per command line
+// flag of gols. The zero value lists the visible entries of a directory
+// by name, except that MaxDepth should be -1 for no depth limit;
+// DefaultOptions returns that.
+type Options struct {
+	All          bool // -a: include hidden entries
+	HiddenOnly   bool // -A: only hidden entries
+	DirsOnly     bool // -D
+	FilesOnly    bool // -F
+	SymlinksOnly bool // -m
+
+	Recursive     bool // -r: walk the whole tree
+	RecursiveFlat bool // -R: list each directory of the tree in turn
+	MaxDepth      int  // -d: depth limit of the tree, -1 for none
+	Follow        bool // -L, --follow: enter symlinked directories, but not loops
+	OneFileSystem bool // --one-file-system: don't enter other file systems than the root's
+
+	TreeStyle   string // --tree-style: "unicode" (the default), "ascii", "rounded" or "none"
+	Collapse    bool   // --collapse: a/b/c for directories with one directory in them
+	FileLimit   int    // --filelimit: directories with more entries are not entered, 0 for no limit
+	HiddenCount bool   // --hidden-count: how many hidden entries each directory has
+
+	Extensions        []string // -e: only these extensions
+	ExcludeExtensions []string // -x
+	Match             []string // --match globs, ** spans directories
+	Regex             []string // --regex patterns matched against names
+	Ignore            []string // --ignore globs
+	IgnoreCase        bool     // --ignore-case
+	Gitignore         string   // --gitignore: "", "hide" or "dim"
+	Predicates        []Predicate
+
+	SortBySize bool // -o
+	SortByTime bool // -t
+
+	Long            bool // -l
+	HumanReadable   bool // -h
+	Sizes           bool // -s
+	OneColumn       bool // -c
+	DirIconLeft     bool // -i
+	Summary         bool // -f
+	SummaryOnly     bool // --summary-only
+	PermissionsOnly bool // -p
+	OwnerOnly       bool // -O
+	TimeOnly        bool // -T
+	GroupOnly       bool // -g
+	Bars            bool // --bars
+	DirSizes        bool // --du: directories show the size of their contents
+	NoIcons         bool // --no-icons: names without icons
+
+	GitStatus bool   // --git
+	GitLog    bool   // --git-log: a column of the long listing
+	Checksum  string // --checksum: sha256, sha512, sha1, md5, blake2b or crc32
+	Sniff     bool   // --sniff
+	Kind      bool   // --kind, which implies Sniff
+
+	// Format names the renderer, as registered with RegisterRenderer.
+	// Empty picks one from the flags above, as gols does without
+	// --format.
+	Format string
+
+	// FS, when set, is listed instead of the OS file system, with paths
+	// in the slash-separated form of io/fs, "." for its root. Symlinks
+	// are read through ReadLinkFS and LstatFS when it implements them.
+	// Archives, the git flags and Watch need the OS file system.
+	FS fs.FS
+}
+
+// DefaultOptions returns the options of gols run without flags.
+func DefaultOptions() Options {
+	return Options{MaxDepth: -1}
+}
+
+// Validate reports the first invalid option: an unknown format, tree
+// style, checksum algorithm or gitignore mode, a negative file limit, or
+// a malformed pattern.
+func (o Options) Validate() error {
+	if o.Format != "" {
+		if _, found := LookupRenderer(o.Format); !found {
+			return fmt.Errorf("unknown format %q", o.Format)
+		}
+	}
+	if o.Checksum != "" {
+		if _, found := checksumAlgorithms[o.Checksum]; !found {
+			return fmt.Errorf("unknown checksum algorithm %q", o.Checksum)
+		}
+	}
+	if _, found := treeStyles[o.TreeStyle]; o.TreeStyle != "" && !found {
+		return fmt.Errorf("unknown tree style %q", o.TreeStyle)
+	}
+	if o.FileLimit < 0 {
+		return fmt.Errorf("negative file limit %d", o.FileLimit)
+	}
+	switch o.Gitignore {
+	case "", "hide", "dim":
+	default:
+		return fmt.Errorf("unknown gitignore mode %q", o.Gitignore)
+	}
+	if o.RecursiveFlat && (o.Recursive || o.Format == "tree") {
+		return errors.New("RecursiveFlat (-R) and the tree view exclude each other")
+	}
+	if o.FS != nil && (o.GitStatus || o.GitLog || o.Gitignore != "") {
+		return errors.New("the git options need the OS file system")
+	}
+	m := o.nameMatcher()
+	return m.compile()
+}
+
+// FormatName returns the name of the renderer o selects: Format when
+// set, and otherwise the one the flags imply.
+func (o Options) FormatName() string {
+	switch {
+	case o.Format != "":
+		return o.Format
+	case o.GroupOnly:
+		return "group"
+	case o.PermissionsOnly:
+		return "permissions"
+	case o.OwnerOnly:
+		return "owner"
+	case o.TimeOnly:
+		return "time"
+	case o.Recursive:
+		return "tree"
+	case o.Long:
+		return "long"
+	case o.Sizes:
+		return "size"
+	case o.OneColumn:
+		return "oneline"
+	default:
+		return "grid"
+	}
+}
+
+func (o Options) nameMatcher() nameMatcher {
+	return nameMatcher{
+		extensions:   o.Extensions,
+		excludedExts: o.ExcludeExtensions,
+		globs:        o.Match,
+		regexSources: o.Regex,
+		ignores:      o.Ignore,
+		ignoreCase:   o.IgnoreCase,
+	}
+}
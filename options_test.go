@@ -0,0 +1,64 @@
+package gols
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		err  string
+	}{
+		{name: "defaults", opts: DefaultOptions()},
+		{name: "zero value", opts: Options{}},
+		{name: "everything valid", opts: Options{Format: "json", Checksum: "md5", TreeStyle: "ascii", FileLimit: 10, Gitignore: "dim", Match: []string{"**/*.go"}}},
+		{name: "format", opts: Options{Format: "xml"}, err: `unknown format "xml"`},
+		{name: "checksum", opts: Options{Checksum: "sha384"}, err: `unknown checksum algorithm "sha384"`},
+		{name: "tree style", opts: Options{TreeStyle: "fancy"}, err: `unknown tree style "fancy"`},
+		{name: "file limit", opts: Options{FileLimit: -1}, err: "negative file limit -1"},
+		{name: "gitignore", opts: Options{Gitignore: "show"}, err: `unknown gitignore mode "show"`},
+		{name: "tree and sections", opts: Options{Recursive: true, RecursiveFlat: true}, err: "exclude each other"},
+		{name: "tree format and sections", opts: Options{Format: "tree", RecursiveFlat: true}, err: "exclude each other"},
+		{name: "git on an fs.FS", opts: Options{FS: fstest.MapFS{}, GitStatus: true}, err: "need the OS file system"},
+		{name: "gitignore on an fs.FS", opts: Options{FS: fstest.MapFS{}, Gitignore: "hide"}, err: "need the OS file system"},
+		{name: "pattern", opts: Options{Ignore: []string{"[x"}}, err: `invalid pattern "[x"`},
+	}
+	for _, test := range tests {
+		err := test.opts.Validate()
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%s: Validate = %v", test.name, err)
+		case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Errorf("%s: Validate = %v, want %q", test.name, err, test.err)
+		}
+	}
+}
+
+func TestFormatName(t *testing.T) {
+	tests := []struct {
+		opts Options
+		want string
+	}{
+		{Options{}, "grid"},
+		{Options{Format: "json", Long: true}, "json"},
+		{Options{Long: true}, "long"},
+		{Options{Long: true, Recursive: true}, "tree"},
+		{Options{Recursive: true, GroupOnly: true}, "group"},
+		{Options{PermissionsOnly: true, OwnerOnly: true}, "permissions"},
+		{Options{OwnerOnly: true}, "owner"},
+		{Options{TimeOnly: true}, "time"},
+		{Options{Sizes: true, OneColumn: true}, "size"},
+		{Options{OneColumn: true}, "oneline"},
+	}
+	for _, test := range tests {
+		if got := test.opts.FormatName(); got != test.want {
+			t.Errorf("FormatName of %+v = %s, want %s", test.opts, got, test.want)
+		}
+		if _, found := LookupRenderer(test.want); !found {
+			t.Errorf("no renderer %s", test.want)
+		}
+	}
+}
@@ -0,0 +1,358 @@
+package gols
+
+import (
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Predicate reports whether an entry of directory should be kept. The
+// constructors below build the find-style filters of the command line
+// (--size, --newer, --older-than, --user, --group, --perm, --empty).
+type Predicate func(file fs.DirEntry, directory string) bool
+
+func (ls *lister) filterPredicates(files []os.DirEntry, directory string) []os.DirEntry {
+	var result []os.DirEntry
+	for _, file := range files {
+		if ls.matchesPredicates(file, directory) {
+			result = append(result, file)
+		}
+	}
+	return result
+}
+
+// matchesPredicates reports whether every one of Options.Predicates
+// accepts file, an entry of directory.
+func (ls *lister) matchesPredicates(file os.DirEntry, directory string) bool {
+	if len(ls.opts.Predicates) == 0 {
+		return true
+	}
+	entry := listedDirEntry{file, ls}
+	for _, pred := range ls.opts.Predicates {
+		if !pred(entry, directory) {
+			return false
+		}
+	}
+	return true
+}
+
+// listedDirEntry is a DirEntry handed to predicates, which remembers the
+// call it was read by, so that EmptyPredicate reads directories from the
+// same file system.
+type listedDirEntry struct {
+	fs.DirEntry
+	ls *lister
+}
+
+// SizePredicate parses find-style sizes: "+10M" is more than 10 MiB,
+// "-4k" is less than 4 KiB and "0" is exactly zero bytes. Like find, sizes
+// are rounded up to the unit before comparing.
+func SizePredicate(value string) (Predicate, error) {
+	cmp, rest := splitSign(value)
+
+	unit := int64(1)
+	rest = strings.TrimSuffix(strings.TrimSuffix(rest, "iB"), "B")
+	if n := len(rest); n > 0 {
+		switch rest[n-1] {
+		case 'c':
+			rest = rest[:n-1]
+		case 'k', 'K':
+			unit, rest = 1<<10, rest[:n-1]
+		case 'M':
+			unit, rest = 1<<20, rest[:n-1]
+		case 'G':
+			unit, rest = 1<<30, rest[:n-1]
+		case 'T':
+			unit, rest = 1<<40, rest[:n-1]
+		}
+	}
+
+	n, err := strconv.ParseInt(rest, 10, 64)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid size %q", value)
+	}
+
+	return func(file fs.DirEntry, directory string) bool {
+		info, err := file.Info()
+		if err != nil {
+			return false
+		}
+		size := (info.Size() + unit - 1) / unit
+		switch cmp {
+		case '+':
+			return size > n
+		case '-':
+			return size < n
+		default:
+			return size == n
+		}
+	}, nil
+}
+
+// NewerPredicate keeps entries modified after the reference time and
+// OlderPredicate those modified before it. The value is an age such as
+// "2d", "3h" or "1w", a date such as "2024-01-31", or the path of a file
+// whose modification time is used.
+func NewerPredicate(value string) (Predicate, error) {
+	return timePredicate(value, true)
+}
+
+// OlderPredicate is the counterpart of NewerPredicate.
+func OlderPredicate(value string) (Predicate, error) {
+	return timePredicate(value, false)
+}
+
+func timePredicate(value string, newer bool) (Predicate, error) {
+	ref, err := parseReferenceTime(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(file fs.DirEntry, directory string) bool {
+		info, err := file.Info()
+		if err != nil {
+			return false
+		}
+		if newer {
+			return info.ModTime().After(ref)
+		}
+		return info.ModTime().Before(ref)
+	}, nil
+}
+
+func parseReferenceTime(value string) (time.Time, error) {
+	if age, err := parseAge(value); err == nil {
+		return time.Now().Add(-age), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	info, err := os.Stat(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid age, date or file %q", value)
+	}
+	return info.ModTime(), nil
+}
+
+// parseAge reads a number followed by its unit, such as 90s, 1.5h or
+// 2w. The unit is required, so that a file named 30 is not taken for an
+// age.
+func parseAge(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, fmt.Errorf("empty age")
+	}
+
+	var unit time.Duration
+	switch value[len(value)-1] {
+	case 's':
+		unit = time.Second
+	case 'm':
+		unit = time.Minute
+	case 'h':
+		unit = time.Hour
+	case 'd':
+		unit = 24 * time.Hour
+	case 'w':
+		unit = 7 * 24 * time.Hour
+	case 'y':
+		unit = 365 * 24 * time.Hour
+	default:
+		return 0, fmt.Errorf("age %q has no unit", value)
+	}
+
+	n, err := strconv.ParseFloat(value[:len(value)-1], 64)
+	if err != nil || n < 0 || math.IsNaN(n) || n*float64(unit) > math.MaxInt64 {
+		return 0, fmt.Errorf("invalid age %q", value)
+	}
+	return time.Duration(n * float64(unit)), nil
+}
+
+// UserPredicate keeps entries owned by a user name or uid.
+func UserPredicate(value string) (Predicate, error) {
+	uid, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		u, lookupErr := user.Lookup(value)
+		if lookupErr != nil {
+			return nil, fmt.Errorf("unknown user %q", value)
+		}
+		uid, _ = strconv.ParseUint(u.Uid, 10, 32)
+	}
+
+	return func(file fs.DirEntry, directory string) bool {
+		info, err := file.Info()
+		if err != nil {
+			return false
+		}
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		return ok && uint64(stat.Uid) == uid
+	}, nil
+}
+
+// GroupPredicate keeps entries owned by a group name or gid.
+func GroupPredicate(value string) (Predicate, error) {
+	gid, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		g, lookupErr := user.LookupGroup(value)
+		if lookupErr != nil {
+			return nil, fmt.Errorf("unknown group %q", value)
+		}
+		gid, _ = strconv.ParseUint(g.Gid, 10, 32)
+	}
+
+	return func(file fs.DirEntry, directory string) bool {
+		info, err := file.Info()
+		if err != nil {
+			return false
+		}
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		return ok && uint64(stat.Gid) == gid
+	}, nil
+}
+
+// PermPredicate follows find(1): "MODE" matches the permission bits
+// exactly, "-MODE" requires all of them and "/MODE" any of them. MODE is
+// octal (644) or symbolic (u+w,o+w).
+func PermPredicate(value string) (Predicate, error) {
+	cmp, rest := byte(0), value
+	if strings.HasPrefix(value, "-") || strings.HasPrefix(value, "/") {
+		cmp, rest = value[0], value[1:]
+	}
+
+	want, err := parseMode(rest)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(file fs.DirEntry, directory string) bool {
+		info, err := file.Info()
+		if err != nil {
+			return false
+		}
+		mode := unixMode(info.Mode())
+		switch cmp {
+		case '-':
+			return mode&want == want
+		case '/':
+			return want == 0 || mode&want != 0
+		default:
+			return mode == want
+		}
+	}, nil
+}
+
+// unixMode converts the portable os.FileMode bits back into the
+// traditional 07777 permission word.
+func unixMode(mode os.FileMode) uint32 {
+	perm := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		perm |= 04000
+	}
+	if mode&os.ModeSetgid != 0 {
+		perm |= 02000
+	}
+	if mode&os.ModeSticky != 0 {
+		perm |= 01000
+	}
+	return perm
+}
+
+func parseMode(value string) (uint32, error) {
+	if value == "" {
+		return 0, fmt.Errorf("empty mode")
+	}
+	if n, err := strconv.ParseUint(value, 8, 32); err == nil {
+		if n > 07777 {
+			return 0, fmt.Errorf("invalid mode %q", value)
+		}
+		return uint32(n), nil
+	}
+
+	var mode uint32
+	for _, clause := range strings.Split(value, ",") {
+		i := strings.IndexAny(clause, "+-=")
+		if i < 0 {
+			return 0, fmt.Errorf("invalid mode %q", value)
+		}
+
+		var who uint32
+		for _, c := range clause[:i] {
+			switch c {
+			case 'u':
+				who |= 04700
+			case 'g':
+				who |= 02070
+			case 'o':
+				who |= 01007
+			case 'a':
+				who |= 07777
+			default:
+				return 0, fmt.Errorf("invalid mode %q", value)
+			}
+		}
+		if who == 0 {
+			who = 07777
+		}
+		action := clause[i]
+
+		var bits uint32
+		for _, c := range clause[i+1:] {
+			switch c {
+			case 'r':
+				bits |= 0444
+			case 'w':
+				bits |= 0222
+			case 'x':
+				bits |= 0111
+			case 's':
+				bits |= 06000
+			case 't':
+				bits |= 01000
+			default:
+				return 0, fmt.Errorf("invalid mode %q", value)
+			}
+		}
+		bits &= who
+
+		switch action {
+		case '+':
+			mode |= bits
+		case '-':
+			mode &^= bits
+		case '=':
+			mode = mode&^who | bits
+		}
+	}
+	return mode, nil
+}
+
+// EmptyPredicate keeps empty regular files and empty directories.
+func EmptyPredicate() Predicate {
+	return func(file fs.DirEntry, directory string) bool {
+		if file.IsDir() {
+			ls := defaultLister()
+			if listed, ok := file.(listedDirEntry); ok {
+				ls = listed.ls
+			}
+			return ls.dirIsEmpty(filepath.Join(directory, file.Name()))
+		}
+		info, err := file.Info()
+		if err != nil {
+			return false
+		}
+		return info.Mode().IsRegular() && info.Size() == 0
+	}
+}
+
+func splitSign(value string) (byte, string) {
+	if strings.HasPrefix(value, "+") || strings.HasPrefix(value, "-") {
+		return value[0], value[1:]
+	}
+	return 0, value
+}
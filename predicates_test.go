@@ -0,0 +1,189 @@
+package gols
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestPredicates(t *testing.T) {
+	now := time.Now()
+	fsys := fstest.MapFS{
+		"empty.txt":    {ModTime: now},
+		"small.txt":    {Data: []byte("12345"), Mode: 0o644, ModTime: now.Add(-2 * time.Hour)},
+		"kilo.bin":     {Data: make([]byte, 1024), Mode: 0o600, ModTime: now.Add(-3 * 24 * time.Hour)},
+		"big.bin":      {Data: make([]byte, 3000), Mode: 0o755, ModTime: now.Add(-30 * 24 * time.Hour)},
+		"setuid":       {Data: []byte("x"), Mode: 0o755 | fs.ModeSetuid, ModTime: now},
+		"emptydir":     {Mode: fs.ModeDir | 0o755, ModTime: now},
+		"full":         {Mode: fs.ModeDir | 0o700, ModTime: now},
+		"full/file.go": {Data: []byte("package full\n"), ModTime: now},
+	}
+
+	must := func(p Predicate, err error) Predicate {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	tests := []struct {
+		name string
+		pred Predicate
+		want string
+	}{
+		{"size exactly zero", must(SizePredicate("0")), "empty.txt emptydir full"},
+		{"size over 1k", must(SizePredicate("+1k")), "big.bin"},
+		{"size under 1k", must(SizePredicate("-1k")), "empty.txt emptydir full"},
+		{"size of 1k rounded up", must(SizePredicate("1K")), "kilo.bin setuid small.txt"},
+		{"size in bytes", must(SizePredicate("5c")), "small.txt"},
+		{"size with a B suffix", must(SizePredicate("+2KiB")), "big.bin"},
+		{"newer than a day", must(NewerPredicate("1d")), "empty.txt emptydir full setuid small.txt"},
+		{"older than a week", must(OlderPredicate("1w")), "big.bin"},
+		{"older than 90 minutes", must(OlderPredicate("90m")), "big.bin kilo.bin small.txt"},
+		{"perm exactly", must(PermPredicate("644")), "small.txt"},
+		{"perm all of", must(PermPredicate("-u+x,g+x")), "big.bin emptydir setuid"},
+		{"perm any of", must(PermPredicate("/4000")), "setuid"},
+		{"perm symbolic exactly", must(PermPredicate("u=rw")), "kilo.bin"},
+		{"empty", EmptyPredicate(), "empty.txt emptydir"},
+	}
+	for _, test := range tests {
+		opts := DefaultOptions()
+		opts.FS = fsys
+		opts.Predicates = []Predicate{test.pred}
+		entries, err := List(context.Background(), ".", opts)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name)
+		}
+		if got := strings.Join(names, " "); got != test.want {
+			t.Errorf("%s: listed %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestPredicateErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		make func() (Predicate, error)
+	}{
+		{"size without a number", func() (Predicate, error) { return SizePredicate("+k") }},
+		{"negative size", func() (Predicate, error) { return SizePredicate("--1") }},
+		{"size with a bad unit", func() (Predicate, error) { return SizePredicate("3q") }},
+		{"bad age", func() (Predicate, error) { return NewerPredicate("soon") }},
+		{"bad date", func() (Predicate, error) { return OlderPredicate("2024-13-01") }},
+		{"mode over 07777", func() (Predicate, error) { return PermPredicate("17777") }},
+		{"mode without an action", func() (Predicate, error) { return PermPredicate("u") }},
+		{"mode with a bad class", func() (Predicate, error) { return PermPredicate("z+x") }},
+		{"mode with a bad bit", func() (Predicate, error) { return PermPredicate("u+q") }},
+		{"empty mode", func() (Predicate, error) { return PermPredicate("-") }},
+		{"unknown user", func() (Predicate, error) { return UserPredicate("no such user") }},
+		{"unknown group", func() (Predicate, error) { return GroupPredicate("no such group") }},
+	}
+	for _, test := range tests {
+		if _, err := test.make(); err == nil {
+			t.Errorf("%s: no error", test.name)
+		}
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := map[string]time.Duration{
+		"30s":  30 * time.Second,
+		"45s":  45 * time.Second,
+		"5m":   5 * time.Minute,
+		"1.5h": 90 * time.Minute,
+		"2d":   48 * time.Hour,
+		"1w":   7 * 24 * time.Hour,
+		"1y":   365 * 24 * time.Hour,
+	}
+	for value, want := range tests {
+		if got, err := parseAge(value); err != nil || got != want {
+			t.Errorf("parseAge(%q) = %v, %v, want %v", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"", "d", "-1d", "1x", "30", "NaNs", "infd", "+Infh", "1e300y"} {
+		if _, err := parseAge(value); err == nil {
+			t.Errorf("parseAge(%q) succeeded", value)
+		}
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	tests := map[string]uint32{
+		"0":           0,
+		"644":         0o644,
+		"4755":        0o4755,
+		"u+w":         0o200,
+		"a+r":         0o444,
+		"+x":          0o111,
+		"u+rwx,g+rx":  0o750,
+		"a+rwx,o-w":   0o775,
+		"u=rw,g=r":    0o640,
+		"a+rw,g=x":    0o616,
+		"u+s,g+s,o+t": 0o7000,
+	}
+	for value, want := range tests {
+		if got, err := parseMode(value); err != nil || got != want {
+			t.Errorf("parseMode(%q) = %04o, %v, want %04o", value, got, err, want)
+		}
+	}
+}
+
+func TestReferenceTimeOfFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "ref")
+	if err := os.WriteFile(name, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2024, 1, 31, 12, 0, 0, 0, time.Local)
+	if err := os.Chtimes(name, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := parseReferenceTime(name); err != nil || !got.Equal(mtime) {
+		t.Errorf("parseReferenceTime(file) = %v, %v, want %v", got, err, mtime)
+	}
+	want := time.Date(2024, 1, 31, 0, 0, 0, 0, time.Local)
+	if got, err := parseReferenceTime("2024-01-31"); err != nil || !got.Equal(want) {
+		t.Errorf("parseReferenceTime(date) = %v, %v, want %v", got, err, want)
+	}
+}
+
+func TestOwnerPredicates(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"mine": "x"})
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uid, gid := strconv.Itoa(os.Getuid()), strconv.Itoa(os.Getgid())
+	other := strconv.Itoa(os.Getuid() + 1)
+	tests := []struct {
+		name string
+		make func(string) (Predicate, error)
+		id   string
+		want bool
+	}{
+		{"own uid", UserPredicate, uid, true},
+		{"other uid", UserPredicate, other, false},
+		{"own gid", GroupPredicate, gid, true},
+		{"other gid", GroupPredicate, strconv.Itoa(os.Getgid() + 1), false},
+	}
+	for _, test := range tests {
+		pred, err := test.make(test.id)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if got := pred(files[0], dir); got != test.want {
+			t.Errorf("%s: %v, want %v", test.name, got, test.want)
+		}
+	}
+}
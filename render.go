@@ -0,0 +1,190 @@
+package gols
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Listing is what a Renderer draws: the entries gols selected for one
+// path, with every entry already stat'ed and filtered.
+type Listing struct {
+	Root string // the path that was listed
+	Dir  string // the directory of the top-level entries
+
+	// Tree is set when Entries are a whole tree in the order of Walk,
+	// with Depth telling the levels apart.
+	Tree    bool
+	Entries []Entry
+
+	Options Options
+
+	ls     *lister // the call the listing was made by
+	hidden int     // hidden entries left out of Entries
+
+	// decorate, when set, returns what renderTree shows before and
+	// after the name of an entry, as --diff does with its markers.
+	decorate func(Entry) (before, after string)
+}
+
+// lister returns the call l was made by, or for a Listing built by hand
+// a new one with its options.
+func (l *Listing) lister() *lister {
+	if l.ls == nil {
+		ls, err := newLister(l.Options)
+		if err != nil {
+			ls = defaultLister()
+		}
+		l.ls = ls
+	}
+	return l.ls
+}
+
+// Renderer draws a listing. The built-in renderers are grid, oneline,
+// long, tree and json, plus size, permissions, owner, time and group
+// for the single-column flags; others can be added with
+// RegisterRenderer and picked with --format=NAME.
+type Renderer interface {
+	Render(w io.Writer, l *Listing) error
+}
+
+// RendererFunc adapts a function to the Renderer interface.
+type RendererFunc func(w io.Writer, l *Listing) error
+
+// Render calls f(w, l).
+func (f RendererFunc) Render(w io.Writer, l *Listing) error {
+	return f(w, l)
+}
+
+var (
+	renderersMu sync.RWMutex
+	renderers   map[string]Renderer
+)
+
+// The built-in renderers are registered in init, as a listing they draw
+// validates its options against them.
+func init() {
+	renderers = map[string]Renderer{
+		"grid":        RendererFunc(renderGrid),
+		"oneline":     RendererFunc(renderOneline),
+		"long":        RendererFunc(renderLong),
+		"tree":        RendererFunc(renderTree),
+		"json":        RendererFunc(renderJSON),
+		"size":        RendererFunc(renderSizes),
+		"permissions": RendererFunc(renderPermissions),
+		"owner":       RendererFunc(renderOwners),
+		"time":        RendererFunc(renderTimes),
+		"group":       RendererFunc(renderGroups),
+	}
+}
+
+// RegisterRenderer makes r available under name, for Options.Format
+// and --format. Like database/sql.Register, it panics when name is
+// taken or r is nil, and is meant to be called from an init function.
+func RegisterRenderer(name string, r Renderer) {
+	renderersMu.Lock()
+	defer renderersMu.Unlock()
+	if r == nil {
+		panic("gols: RegisterRenderer of nil renderer " + name)
+	}
+	if _, found := renderers[name]; found {
+		panic("gols: RegisterRenderer called twice for " + name)
+	}
+	renderers[name] = r
+}
+
+// LookupRenderer returns the renderer registered under name.
+func LookupRenderer(name string) (Renderer, bool) {
+	renderersMu.RLock()
+	defer renderersMu.RUnlock()
+	r, found := renderers[name]
+	return r, found
+}
+
+// Renderers returns the names of the registered renderers, sorted.
+func Renderers() []string {
+	renderersMu.RLock()
+	defer renderersMu.RUnlock()
+	names := make([]string, 0, len(renderers))
+	for name := range renderers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// summary returns the -f summary of the entries of l.
+func (l *Listing) summary() *summary {
+	s := newSummary(l.lister(), l.Dir)
+	s.hidden = l.hidden
+	for _, entry := range l.Entries {
+		s.add(entry.file, entry.Dir)
+	}
+	return s
+}
+
+// writeSummary ends the output of a text renderer with the -f summary
+// when it is asked for.
+func writeSummary(w io.Writer, l *Listing) {
+	if !l.Options.Summary {
+		return
+	}
+	fmt.Fprintln(w)
+	l.summary().print(w)
+}
+
+// jsonEntry is how the json renderer writes an Entry.
+type jsonEntry struct {
+	Name    string    `json:"name"`
+	Path    string    `json:"path"`
+	Depth   int       `json:"depth,omitempty"`
+	Type    string    `json:"type"`
+	Size    int64     `json:"size"`
+	Mode    string    `json:"mode"`
+	ModTime time.Time `json:"mtime"`
+	Target  string    `json:"target,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// renderJSON writes the entries as an indented JSON array, without
+// colours or icons, for scripts.
+func renderJSON(w io.Writer, l *Listing) error {
+	entries := make([]jsonEntry, 0, len(l.Entries))
+	for _, entry := range l.Entries {
+		je := jsonEntry{
+			Name:    entry.Name,
+			Path:    entry.Path,
+			Depth:   entry.Depth,
+			Type:    entryType(entry.Info.Mode()),
+			Size:    entry.Info.Size(),
+			Mode:    entry.Info.Mode().String(),
+			ModTime: entry.Info.ModTime(),
+			Target:  entry.LinkTarget,
+		}
+		if entry.Err != nil {
+			je.Error = entry.Err.Error()
+		}
+		entries = append(entries, je)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
+
+func entryType(mode fs.FileMode) string {
+	switch {
+	case mode.IsDir():
+		return "dir"
+	case mode&fs.ModeSymlink != 0:
+		return "symlink"
+	case mode.IsRegular():
+		return "file"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,83 @@
+package gols
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestRegisterRenderer(t *testing.T) {
+	count := RendererFunc(func(w io.Writer, l *Listing) error {
+		_, err := fmt.Fprintln(w, len(l.Entries), l.Tree)
+		return err
+	})
+	RegisterRenderer("test-count", count)
+
+	found := false
+	for _, name := range Renderers() {
+		found = found || name == "test-count"
+	}
+	if _, ok := LookupRenderer("test-count"); !ok || !found {
+		t.Fatal("the registered renderer cannot be found")
+	}
+
+	opts := DefaultOptions()
+	opts.FS = fstest.MapFS{"a": {}, "b/c": {}}
+	opts.Format = "test-count"
+	var out bytes.Buffer
+	if _, err := Print(&out, ".", opts); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); got != "2 false\n" {
+		t.Errorf("the registered renderer printed %q", got)
+	}
+
+	for name, r := range map[string]Renderer{"test-count": count, "test-nil": nil, "grid": count} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterRenderer(%q, %v) did not panic", name, r)
+				}
+			}()
+			RegisterRenderer(name, r)
+		}()
+	}
+}
+
+func TestRenderJSON(t *testing.T) {
+	mtime := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	opts := DefaultOptions()
+	opts.FS = fstest.MapFS{
+		"a.txt":   {Data: []byte("abc"), Mode: 0o644, ModTime: mtime},
+		"dir":     {Mode: fs.ModeDir | 0o755, ModTime: mtime},
+		"dir/b":   {Data: []byte("b"), Mode: 0o600, ModTime: mtime},
+		"fifo":    {Mode: fs.ModeNamedPipe | 0o644, ModTime: mtime},
+		".hidden": {ModTime: mtime},
+	}
+	opts.Format = "json"
+	opts.Recursive = true
+
+	var out bytes.Buffer
+	if _, err := Print(&out, ".", opts); err != nil {
+		t.Fatal(err)
+	}
+	var got []jsonEntry
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("%v\n%s", err, out.String())
+	}
+	want := []jsonEntry{
+		{Name: "a.txt", Path: "a.txt", Type: "file", Size: 3, Mode: "-rw-r--r--", ModTime: mtime},
+		{Name: "dir", Path: "dir", Type: "dir", Mode: "drwxr-xr-x", ModTime: mtime},
+		{Name: "b", Path: "dir/b", Depth: 1, Type: "file", Size: 1, Mode: "-rw-------", ModTime: mtime},
+		{Name: "fifo", Path: "fifo", Type: "other", Mode: "prw-r--r--", ModTime: mtime},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("json =\n%+v\nwant\n%+v", got, want)
+	}
+}
@@ -0,0 +1,117 @@
+package gols
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+// walkSections calls fn with the listing of root and then, depth first,
+// of each directory below it that the tree view would enter. A section
+// that cannot be read is passed with a nil listing and its error; one
+// the filters leave empty has no entries. The name filters and
+// predicates pick what a section shows, not which directories have one.
+func (ls *lister) walkSections(ctx context.Context, root string, fn func(dir string, depth int, l *Listing, err error) error) error {
+	info, err := ls.statPath(root)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		l, err := ls.newListing(ctx, root)
+		if err != nil {
+			return err
+		}
+		return fn(root, 0, l, nil)
+	}
+
+	ls.treeRoot = root
+	if err := ls.section(ctx, root, 0, fn); err != nil {
+		return err
+	}
+	return ls.withoutIncludes().walkEntries(ctx, root, func(entry Entry) error {
+		if !entry.entered {
+			return nil
+		}
+		return ls.section(ctx, entry.Path, entry.Depth+1, fn)
+	})
+}
+
+// section lists dir for walkSections.
+func (ls *lister) section(ctx context.Context, dir string, depth int, fn func(dir string, depth int, l *Listing, err error) error) error {
+	l, err := ls.newListing(ctx, dir)
+	if err == errNoFiles {
+		l, err = &Listing{Root: dir, Dir: dir, Options: ls.opts, ls: ls}, nil
+	}
+	if err != nil {
+		return fn(dir, depth, nil, err)
+	}
+	return fn(dir, depth, l, nil)
+}
+
+// collectSections returns the entries of every section under root, with
+// Rel and Depth relative to root as in a tree listing.
+func (ls *lister) collectSections(ctx context.Context, root string) ([]Entry, error) {
+	var entries []Entry
+	err := ls.walkSections(ctx, root, func(dir string, depth int, l *Listing, err error) error {
+		if err != nil {
+			for i := range entries {
+				if entries[i].Path == dir {
+					entries[i].Err = err
+				}
+			}
+			if depth == 0 {
+				return err
+			}
+			return nil
+		}
+		for _, entry := range l.Entries {
+			entry.Rel = ls.treeRelPath(entry.Dir, entry.Name)
+			entry.Depth = depth
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// printSections prints each section of root under a "path:" header,
+// with the renderer the options select. It reports false when no
+// section had anything to list.
+func (ls *lister) printSections(w io.Writer, root string) (bool, error) {
+	renderer, _ := LookupRenderer(ls.opts.FormatName())
+	found := false
+	first := true
+	err := ls.walkSections(context.Background(), root, func(dir string, depth int, l *Listing, err error) error {
+		if err != nil && depth == 0 {
+			return err
+		}
+		if !first {
+			fmt.Fprintln(w)
+		}
+		first = false
+		// A file given as root is listed without a header.
+		if l == nil || l.Dir == dir {
+			fmt.Fprintf(w, "%s%s:%s\n", blue, dir, reset)
+		}
+
+		switch {
+		case err != nil && os.IsPermission(err):
+			fmt.Fprintf(w, "%sError: Permission denied for %s%s\n", red, dir, reset)
+			return nil
+		case err != nil:
+			fmt.Fprintf(w, "%sError reading directory %s: %v%s\n", red, dir, err, reset)
+			return nil
+		case len(l.Entries) == 0:
+			return nil
+		}
+
+		found = true
+		if ls.opts.SummaryOnly {
+			l.summary().print(w)
+			return nil
+		}
+		return renderer.Render(w, l)
+	})
+	return found, err
+}
@@ -0,0 +1,257 @@
+package gols
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// snapshot is the JSON document written by --snapshot. Recursive,
+// MaxDepth, Hidden, Follow and OneFileSystem record how the tree was
+// walked, so --since walks it the same way.
+type snapshot struct {
+	Root          string          `json:"root"`
+	Created       time.Time       `json:"created"`
+	Recursive     bool            `json:"recursive"`
+	MaxDepth      int             `json:"max_depth"`
+	Hidden        bool            `json:"hidden"`
+	Follow        bool            `json:"follow,omitempty"`
+	OneFileSystem bool            `json:"one_file_system,omitempty"`
+	Entries       []snapshotEntry `json:"entries"`
+}
+
+// snapshotEntry is the metadata recorded for each entry, under its
+// slash-separated path relative to the root.
+type snapshotEntry struct {
+	Path    string      `json:"path"`
+	Mode    os.FileMode `json:"mode"`
+	Size    int64       `json:"size"`
+	ModTime time.Time   `json:"mtime"`
+	UID     uint32      `json:"uid"`
+	GID     uint32      `json:"gid"`
+	Target  string      `json:"target,omitempty"`
+}
+
+// takeSnapshot records the entries under root, the whole tree with -r.
+func (ls *lister) takeSnapshot(root string) (*snapshot, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	snap := &snapshot{
+		Root:      abs,
+		Created:   time.Now(),
+		Recursive: ls.recursive,
+		MaxDepth:  ls.opts.MaxDepth,
+		Hidden:    ls.showHidden,
+
+		Follow:        ls.opts.Follow,
+		OneFileSystem: ls.opts.OneFileSystem,
+	}
+	snap.Entries = ls.scanSnapshot(root, snap)
+	return snap, nil
+}
+
+// scanSnapshot walks root the way snap was recorded. The name filters
+// and predicates are left out, as the snapshot does not record them and
+// --since would otherwise report what they hide as removed.
+func (ls *lister) scanSnapshot(root string, snap *snapshot) []snapshotEntry {
+	scan := ls.unfiltered()
+	scan.showHidden = snap.Hidden
+	scan.opts.MaxDepth = 0
+	if snap.Recursive {
+		scan.opts.MaxDepth = snap.MaxDepth
+	}
+	scan.opts.Follow = snap.Follow
+	scan.opts.OneFileSystem = snap.OneFileSystem
+
+	var entries []snapshotEntry
+	scan.walkEntries(context.Background(), root, func(e Entry) error {
+		entry := snapshotEntry{
+			Path:    e.Rel,
+			Mode:    e.Info.Mode(),
+			Size:    e.Info.Size(),
+			ModTime: e.Info.ModTime(),
+		}
+		if stat, ok := e.Info.Sys().(*syscall.Stat_t); ok {
+			entry.UID, entry.GID = stat.Uid, stat.Gid
+		}
+		entry.Target = e.LinkTarget
+		// Directory sizes depend on the filesystem, not on what changed.
+		if e.IsDir() {
+			entry.Size = 0
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	return entries
+}
+
+// WriteSnapshot records the metadata of the listing of root, or of the
+// tree with opts.Recursive, as JSON to w, like gols --snapshot. It
+// returns the number of entries recorded.
+func WriteSnapshot(w io.Writer, root string, opts Options) (int, error) {
+	opts.FS = nil
+	ls, err := newLister(opts)
+	if err != nil {
+		return 0, err
+	}
+	snap, err := ls.takeSnapshot(root)
+	if err != nil {
+		return 0, err
+	}
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		return 0, err
+	}
+	return len(snap.Entries), nil
+}
+
+// Since prints to w what changed in root since the snapshot read from r
+// was written, like gols --since. An empty root stands for the
+// directory the snapshot was taken of. It reports whether nothing
+// changed.
+func Since(w io.Writer, r io.Reader, root string, opts Options) (bool, error) {
+	opts.FS = nil
+	ls, err := newLister(opts)
+	if err != nil {
+		return false, err
+	}
+	var snap snapshot
+	if err := json.NewDecoder(r).Decode(&snap); err != nil {
+		return false, fmt.Errorf("reading snapshot: %v", err)
+	}
+	return ls.runSince(w, &snap, root)
+}
+
+// runSince compares root, or the directory the snapshot was taken of
+// when root is empty, with snap and prints the entries that were added
+// (+), removed (-), modified (~) or that only changed permissions or
+// ownership (*). It reports whether nothing changed.
+func (ls *lister) runSince(w io.Writer, snap *snapshot, root string) (bool, error) {
+	if root == "" {
+		root = snap.Root
+	}
+	if info, err := os.Stat(root); err != nil {
+		return false, err
+	} else if !info.IsDir() {
+		return false, fmt.Errorf("%s is not a directory", root)
+	}
+
+	before := map[string]snapshotEntry{}
+	for _, entry := range snap.Entries {
+		before[entry.Path] = entry
+	}
+	after := map[string]snapshotEntry{}
+	for _, entry := range ls.scanSnapshot(root, snap) {
+		after[entry.Path] = entry
+	}
+
+	paths := make([]string, 0, len(before)+len(after))
+	for p := range after {
+		paths = append(paths, p)
+	}
+	for p := range before {
+		if _, found := after[p]; !found {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	fmt.Fprintf(w, "%sChanges in %s since %s%s\n\n", gray, root, snap.Created.Format("2006-01-02 15:04:05"), reset)
+
+	var added, removed, modified, permissions int
+	for _, p := range paths {
+		old, existed := before[p]
+		now, exists := after[p]
+		dir := filepath.Join(root, filepath.FromSlash(path.Dir(p)))
+
+		switch {
+		case !existed:
+			added++
+			fmt.Fprintln(w, green+"+"+reset+" "+ls.formatFindResult(snapshotDirEntry(now), dir, p))
+		case !exists:
+			removed++
+			fmt.Fprintln(w, red+"-"+reset+" "+ls.formatFindResult(snapshotDirEntry(old), dir, p))
+		default:
+			changes, permissionsOnly := compareSnapshotEntries(old, now)
+			if len(changes) == 0 {
+				continue
+			}
+			marker := yellow + "~" + reset
+			if permissionsOnly {
+				marker = magenta + "*" + reset
+				permissions++
+			} else {
+				modified++
+			}
+			fmt.Fprintln(w, marker+" "+ls.formatFindResult(snapshotDirEntry(now), dir, p)+"  "+yellow+strings.Join(changes, ", ")+reset)
+		}
+	}
+
+	if added+removed+modified+permissions > 0 {
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "Added: %s%d%s\n", green, added, reset)
+	fmt.Fprintf(w, "Removed: %s%d%s\n", red, removed, reset)
+	fmt.Fprintf(w, "Modified: %s%d%s\n", yellow, modified, reset)
+	fmt.Fprintf(w, "Permissions changed: %s%d%s\n", magenta, permissions, reset)
+	return added+removed+modified+permissions == 0, nil
+}
+
+// compareSnapshotEntries describes how an entry changed and whether only
+// its permissions or ownership did.
+func compareSnapshotEntries(old, now snapshotEntry) ([]string, bool) {
+	var content, access []string
+	if old.Mode.Type() != now.Mode.Type() {
+		content = append(content, "type")
+	}
+	if old.Size != now.Size {
+		content = append(content, fmt.Sprintf("size %s → %s", formatSize(old.Size, true), formatSize(now.Size, true)))
+	}
+	if old.Target != now.Target {
+		content = append(content, fmt.Sprintf("target %s → %s", old.Target, now.Target))
+	}
+	if !old.ModTime.Equal(now.ModTime) && !now.Mode.IsDir() {
+		content = append(content, "mtime "+now.ModTime.Format("2006-01-02 15:04:05"))
+	}
+
+	if old.Mode.Perm() != now.Mode.Perm() {
+		access = append(access, fmt.Sprintf("mode %s → %s", old.Mode.Perm(), now.Mode.Perm()))
+	}
+	if old.UID != now.UID || old.GID != now.GID {
+		access = append(access, fmt.Sprintf("owner %d:%d → %d:%d", old.UID, old.GID, now.UID, now.GID))
+	}
+	return append(content, access...), len(content) == 0
+}
+
+// snapshotDirEntry turns a recorded entry into a directory entry, so
+// entries that no longer exist still get their icon.
+func snapshotDirEntry(entry snapshotEntry) os.DirEntry {
+	return &archiveEntry{
+		name:       path.Base(entry.Path),
+		size:       entry.Size,
+		mode:       entry.Mode,
+		modTime:    entry.ModTime,
+		linkTarget: entry.Target,
+	}
+}
@@ -0,0 +1,235 @@
+package gols
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ansiEscapes matches the colours gols writes, for tests that compare
+// text.
+var ansiEscapes = regexp.MustCompile("\033\\[[0-9;]*m")
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"a.txt":      "a",
+		".hidden":    "h",
+		"src/lib.go": "package lib\n",
+	})
+	if err := os.Symlink("a.txt", filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		opts  func(*Options)
+		paths []string
+	}{
+		{name: "listing", paths: []string{"a.txt", "link", "src"}},
+		{name: "hidden", opts: func(o *Options) { o.All = true }, paths: []string{".hidden", "a.txt", "link", "src"}},
+		{name: "tree", opts: func(o *Options) { o.Recursive = true }, paths: []string{"a.txt", "link", "src", "src/lib.go"}},
+		{name: "filters left out", opts: func(o *Options) { o.Extensions = []string{"go"} }, paths: []string{"a.txt", "link", "src"}},
+	}
+	for _, test := range tests {
+		opts := DefaultOptions()
+		if test.opts != nil {
+			test.opts(&opts)
+		}
+		var buf bytes.Buffer
+		n, err := WriteSnapshot(&buf, root, opts)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		var snap snapshot
+		if err := json.Unmarshal(buf.Bytes(), &snap); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		var paths []string
+		for _, entry := range snap.Entries {
+			paths = append(paths, entry.Path)
+			switch entry.Path {
+			case "link":
+				if entry.Target != "a.txt" || entry.Mode&os.ModeSymlink == 0 {
+					t.Errorf("%s: link recorded as %+v", test.name, entry)
+				}
+			case "src":
+				if entry.Size != 0 || !entry.Mode.IsDir() {
+					t.Errorf("%s: directory recorded as %+v", test.name, entry)
+				}
+			case "a.txt":
+				if entry.Size != 1 || entry.UID != uint32(os.Getuid()) {
+					t.Errorf("%s: file recorded as %+v", test.name, entry)
+				}
+			}
+		}
+		if n != len(paths) || !reflect.DeepEqual(paths, test.paths) {
+			t.Errorf("%s: recorded %d %v, want %v", test.name, n, paths, test.paths)
+		}
+		if snap.Root != root || snap.Recursive != opts.Recursive || snap.Hidden != opts.All {
+			t.Errorf("%s: snapshot header %+v", test.name, snap)
+		}
+		if strings.Contains(buf.String(), "follow") {
+			t.Errorf("%s: unset follow was written", test.name)
+		}
+	}
+
+	if _, err := WriteSnapshot(new(bytes.Buffer), filepath.Join(root, "a.txt"), DefaultOptions()); err == nil {
+		t.Error("WriteSnapshot of a file succeeded")
+	}
+}
+
+func TestCompareSnapshotEntries(t *testing.T) {
+	mtime := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	file := snapshotEntry{Path: "f", Mode: 0o644, Size: 10, ModTime: mtime, UID: 1000, GID: 1000}
+
+	tests := []struct {
+		name            string
+		change          func(*snapshotEntry)
+		changes         string
+		permissionsOnly bool
+	}{
+		{name: "same", change: func(e *snapshotEntry) {}, permissionsOnly: true},
+		{name: "size", change: func(e *snapshotEntry) { e.Size = 2048 }, changes: "size 10 B → 2.00 KB"},
+		{name: "mtime", change: func(e *snapshotEntry) { e.ModTime = mtime.Add(time.Hour) }, changes: "mtime "},
+		{name: "type", change: func(e *snapshotEntry) { e.Mode = os.ModeSymlink | 0o777; e.Target = "x" }, changes: "type, target  → x, mode -rw-r--r-- → -rwxrwxrwx"},
+		{name: "mode", change: func(e *snapshotEntry) { e.Mode = 0o600 }, changes: "mode -rw-r--r-- → -rw-------", permissionsOnly: true},
+		{name: "owner", change: func(e *snapshotEntry) { e.GID = 0 }, changes: "owner 1000:1000 → 1000:0", permissionsOnly: true},
+		{name: "size and mode", change: func(e *snapshotEntry) { e.Size, e.Mode = 11, 0o755 }, changes: "size 10 B → 11 B, mode -rw-r--r-- → -rwxr-xr-x"},
+	}
+	for _, test := range tests {
+		now := file
+		test.change(&now)
+		changes, permissionsOnly := compareSnapshotEntries(file, now)
+		got := strings.Join(changes, ", ")
+		if !strings.HasPrefix(got, test.changes) || (test.changes == "" && got != "") || permissionsOnly != test.permissionsOnly {
+			t.Errorf("%s: compareSnapshotEntries = %q, %v, want %q, %v", test.name, got, permissionsOnly, test.changes, test.permissionsOnly)
+		}
+	}
+
+	dir := snapshotEntry{Path: "d", Mode: os.ModeDir | 0o755, ModTime: mtime}
+	now := dir
+	now.ModTime = mtime.Add(time.Hour)
+	if changes, _ := compareSnapshotEntries(dir, now); len(changes) != 0 {
+		t.Errorf("a directory's mtime counts as a change: %v", changes)
+	}
+}
+
+// snapshotTime is the modification time TestSince gives a.txt, so that
+// rewriting it only changes its size.
+var snapshotTime = time.Date(2024, 1, 31, 12, 0, 0, 0, time.Local)
+
+func TestSince(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   func(*Options)
+		change func(root string) error
+		want   []string
+	}{
+		{
+			name:   "nothing",
+			change: func(root string) error { return nil },
+		},
+		{
+			name:   "added",
+			change: func(root string) error { return os.WriteFile(filepath.Join(root, "new.txt"), nil, 0o644) },
+			want:   []string{"+ new.txt"},
+		},
+		{
+			name:   "removed",
+			change: func(root string) error { return os.Remove(filepath.Join(root, "a.txt")) },
+			want:   []string{"- a.txt"},
+		},
+		{
+			name: "modified",
+			change: func(root string) error {
+				name := filepath.Join(root, "a.txt")
+				if err := os.WriteFile(name, []byte("longer"), 0o644); err != nil {
+					return err
+				}
+				return os.Chtimes(name, snapshotTime, snapshotTime)
+			},
+			want: []string{"~ a.txt  size 1 B → 6 B"},
+		},
+		{
+			name:   "permissions",
+			change: func(root string) error { return os.Chmod(filepath.Join(root, "a.txt"), 0o600) },
+			want:   []string{"* a.txt  mode -rw-r--r-- → -rw-------"},
+		},
+		{
+			name:   "below the listing",
+			change: func(root string) error { return os.Remove(filepath.Join(root, "src", "lib.go")) },
+		},
+		{
+			name:   "below the tree",
+			opts:   func(o *Options) { o.Recursive = true },
+			change: func(root string) error { return os.Remove(filepath.Join(root, "src", "lib.go")) },
+			want:   []string{"- src/lib.go"},
+		},
+		{
+			name:   "hidden",
+			change: func(root string) error { return os.Remove(filepath.Join(root, ".hidden")) },
+		},
+		{
+			name:   "hidden recorded",
+			opts:   func(o *Options) { o.All = true },
+			change: func(root string) error { return os.Remove(filepath.Join(root, ".hidden")) },
+			want:   []string{"- .hidden"},
+		},
+	}
+	for _, test := range tests {
+		root := t.TempDir()
+		writeFiles(t, root, map[string]string{
+			"a.txt":      "a",
+			".hidden":    "h",
+			"src/lib.go": "package lib\n",
+		})
+		if err := os.Chmod(filepath.Join(root, "a.txt"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(filepath.Join(root, "a.txt"), snapshotTime, snapshotTime); err != nil {
+			t.Fatal(err)
+		}
+		opts := DefaultOptions()
+		opts.NoIcons = true
+		if test.opts != nil {
+			test.opts(&opts)
+		}
+
+		var snap bytes.Buffer
+		if _, err := WriteSnapshot(&snap, root, opts); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if err := test.change(root); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		// --since walks the tree the way the snapshot says, whatever the
+		// options of the call are.
+		var out bytes.Buffer
+		unchanged, err := Since(&out, &snap, "", Options{MaxDepth: -1, NoIcons: true})
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		var got []string
+		for _, line := range strings.Split(ansiEscapes.ReplaceAllString(out.String(), ""), "\n") {
+			if len(line) > 1 && strings.ContainsRune("+-~*", rune(line[0])) && line[1] == ' ' {
+				got = append(got, line)
+			}
+		}
+		if !reflect.DeepEqual(got, test.want) || unchanged != (len(test.want) == 0) {
+			t.Errorf("%s: Since = %v, %q, want %q", test.name, unchanged, got, test.want)
+		}
+	}
+
+	if _, err := Since(new(bytes.Buffer), strings.NewReader("{"), t.TempDir(), DefaultOptions()); err == nil {
+		t.Error("Since read a broken snapshot")
+	}
+}
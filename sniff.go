@@ -0,0 +1,237 @@
+package gols
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fileKind is what sniffing found out about a file: a description for
+// the --kind column and an extension whose icon and colour it borrows.
+type fileKind struct {
+	name string
+	ext  string
+}
+
+// magicSignature identifies a format by the bytes found at offset.
+type magicSignature struct {
+	offset int
+	magic  string
+	kind   fileKind
+}
+
+var magicSignatures = []magicSignature{
+	{0, "\x89PNG\r\n\x1a\n", fileKind{"PNG image", ".png"}},
+	{0, "\xff\xd8\xff", fileKind{"JPEG image", ".jpg"}},
+	{0, "GIF87a", fileKind{"GIF image", ".gif"}},
+	{0, "GIF89a", fileKind{"GIF image", ".gif"}},
+	{8, "WEBP", fileKind{"WebP image", ".webp"}},
+	{0, "%PDF-", fileKind{"PDF document", ".pdf"}},
+	{0, "\x1f\x8b", fileKind{"gzip archive", ".gz"}},
+	{0, "PK\x03\x04", fileKind{"Zip archive", ".zip"}},
+	{0, "PK\x05\x06", fileKind{"Zip archive", ".zip"}},
+	{0, "BZh", fileKind{"bzip2 archive", ".bz2"}},
+	{0, "\xfd7zXZ\x00", fileKind{"xz archive", ".xz"}},
+	{0, "\x28\xb5\x2f\xfd", fileKind{"zstd archive", ".zst"}},
+	{0, "7z\xbc\xaf\x27\x1c", fileKind{"7-Zip archive", ".7z"}},
+	{257, "ustar", fileKind{"tar archive", ".tar"}},
+	{0, "!<arch>\ndebian", fileKind{"Debian package", ".deb"}},
+	{0, "SQLite format 3\x00", fileKind{"SQLite database", ".db"}},
+	{0, "ID3", fileKind{"MP3 audio", ".mp3"}},
+	{0, "OggS", fileKind{"Ogg audio", ".ogg"}},
+	{0, "fLaC", fileKind{"FLAC audio", ".flac"}},
+	{4, "ftyp", fileKind{"MP4 video", ".mp4"}},
+	{0, "\xcf\xfa\xed\xfe", fileKind{"Mach-O executable", ".exe"}},
+}
+
+// shebangKinds maps interpreters named on a #! line to the extension of
+// their scripts.
+var shebangKinds = map[string]string{
+	"sh":      ".sh",
+	"bash":    ".sh",
+	"dash":    ".sh",
+	"zsh":     ".sh",
+	"ksh":     ".sh",
+	"fish":    ".fish",
+	"python":  ".py",
+	"python2": ".py",
+	"python3": ".py",
+	"perl":    ".pl",
+	"ruby":    ".rb",
+	"node":    ".js",
+	"lua":     ".lua",
+	"php":     ".php",
+}
+
+// sniffKind reads the start of a regular file and identifies it by its
+// magic bytes or its #! line. Results are cached per path.
+func (ls *lister) sniffKind(directory, name string) fileKind {
+	path := filepath.Join(directory, name)
+
+	ls.cache.sniffMu.Lock()
+	kind, found := ls.cache.sniffed[path]
+	ls.cache.sniffMu.Unlock()
+	if found {
+		return kind
+	}
+
+	kind = fileKind{name: "data"}
+	if f, err := ls.sysOpen(path); err == nil {
+		head := make([]byte, 512)
+		n, _ := io.ReadFull(f, head)
+		f.Close()
+		kind = identifyContent(head[:n])
+	}
+
+	ls.cache.sniffMu.Lock()
+	ls.cache.sniffed[path] = kind
+	ls.cache.sniffMu.Unlock()
+	return kind
+}
+
+func identifyContent(head []byte) fileKind {
+	if len(head) == 0 {
+		return fileKind{name: "empty"}
+	}
+
+	if bytes.HasPrefix(head, []byte("\x7fELF")) {
+		return identifyELF(head)
+	}
+	if isPE(head) {
+		return fileKind{"Windows executable", ".exe"}
+	}
+
+	for _, sig := range magicSignatures {
+		if len(head) >= sig.offset+len(sig.magic) && string(head[sig.offset:sig.offset+len(sig.magic)]) == sig.magic {
+			return sig.kind
+		}
+	}
+
+	if bytes.HasPrefix(head, []byte("#!")) {
+		line, _, _ := bytes.Cut(head[2:], []byte("\n"))
+		if interpreter := shebangInterpreter(string(line)); interpreter != "" {
+			return fileKind{interpreter + " script", shebangKinds[interpreter]}
+		}
+	}
+
+	if isText(head) {
+		return fileKind{name: "text"}
+	}
+	return fileKind{name: "data"}
+}
+
+// identifyELF tells objects, shared libraries and executables apart.
+// Position-independent executables have the same type as libraries and
+// are recognised by the interpreter they request, in a PT_INTERP
+// program header.
+func identifyELF(head []byte) fileKind {
+	if len(head) < 64 {
+		return fileKind{"ELF executable", ".exe"}
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if head[5] == 2 {
+		order = binary.BigEndian
+	}
+
+	switch order.Uint16(head[16:]) {
+	case 1:
+		return fileKind{"ELF object", ".o"}
+	case 2:
+		return fileKind{"ELF executable", ".exe"}
+	}
+
+	var phoff, phentsize, phnum int
+	if head[4] == 2 {
+		phoff = int(order.Uint64(head[32:]))
+		phentsize, phnum = int(order.Uint16(head[54:])), int(order.Uint16(head[56:]))
+	} else {
+		phoff = int(order.Uint32(head[28:]))
+		phentsize, phnum = int(order.Uint16(head[42:])), int(order.Uint16(head[44:]))
+	}
+	for i := 0; i < phnum && phentsize >= 4; i++ {
+		entry := phoff + i*phentsize
+		if entry < 0 || entry+4 > len(head) {
+			break
+		}
+		if order.Uint32(head[entry:]) == 3 {
+			return fileKind{"ELF executable", ".exe"}
+		}
+	}
+	return fileKind{"ELF shared object", ".exe"}
+}
+
+// isPE reports whether head starts a Windows executable: an MZ header
+// whose little-endian word at 0x3c points at a PE signature. Text that
+// happens to begin with "MZ" has no such signature.
+func isPE(head []byte) bool {
+	if len(head) < 0x40 || !bytes.HasPrefix(head, []byte("MZ")) {
+		return false
+	}
+	offset := int64(binary.LittleEndian.Uint32(head[0x3c:]))
+	return offset+4 <= int64(len(head)) && string(head[offset:offset+4]) == "PE\x00\x00"
+}
+
+// shebangInterpreter returns the program a #! line runs, looking past
+// /usr/bin/env and its options, as in "#!/usr/bin/env -S python3 -u".
+func shebangInterpreter(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" {
+		interpreter = ""
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") && !strings.Contains(field, "=") {
+				interpreter = filepath.Base(field)
+				break
+			}
+		}
+	}
+	return interpreter
+}
+
+// entryKind describes an entry for the --kind column.
+func (ls *lister) entryKind(file os.DirEntry, directory string) string {
+	switch mode := file.Type(); {
+	case mode.IsDir():
+		return "directory"
+	case mode&os.ModeSymlink != 0:
+		return "symlink"
+	case mode&os.ModeNamedPipe != 0:
+		return "pipe"
+	case mode&os.ModeSocket != 0:
+		return "socket"
+	case mode&os.ModeDevice != 0:
+		return "device"
+	case !mode.IsRegular():
+		return "special"
+	}
+	return ls.sniffKind(directory, file.Name()).name
+}
+
+// kindWidth is the width of the --kind column for entries, or 0 when it
+// is not shown.
+func (ls *lister) kindWidth(entries []Entry) int {
+	if !ls.opts.Kind {
+		return 0
+	}
+	width := 0
+	for _, entry := range entries {
+		width = max(width, len(ls.entryKind(entry.file, entry.Dir)))
+	}
+	return width
+}
+
+// kindColumn returns the kind of file padded to width and followed by a
+// space, or "" when --kind is off.
+func (ls *lister) kindColumn(file os.DirEntry, directory string, width int) string {
+	if !ls.opts.Kind {
+		return ""
+	}
+	return white + padRight(ls.entryKind(file, directory), width) + reset + " "
+}
@@ -0,0 +1,89 @@
+package gols
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// peHeader is the start of a Windows executable whose PE signature is
+// at offset.
+func peHeader(offset int) []byte {
+	head := make([]byte, max(offset+4, 0x40))
+	copy(head, "MZ")
+	binary.LittleEndian.PutUint32(head[0x3c:], uint32(offset))
+	if offset+4 <= len(head) {
+		copy(head[offset:], "PE\x00\x00")
+	}
+	return head
+}
+
+// elfHeader is a 64-bit little-endian ELF header of type typ with one
+// program header of type phtype.
+func elfHeader(typ uint16, phtype uint32) []byte {
+	head := make([]byte, 128)
+	copy(head, "\x7fELF\x02\x01")
+	binary.LittleEndian.PutUint16(head[16:], typ)
+	binary.LittleEndian.PutUint64(head[32:], 64)
+	binary.LittleEndian.PutUint16(head[54:], 56)
+	binary.LittleEndian.PutUint16(head[56:], 1)
+	binary.LittleEndian.PutUint32(head[64:], phtype)
+	return head
+}
+
+func TestIdentifyContent(t *testing.T) {
+	tar := make([]byte, 512)
+	copy(tar, "file.txt")
+	copy(tar[257:], "ustar\x0000")
+
+	tests := []struct {
+		name string
+		head []byte
+		want string
+	}{
+		{"empty", nil, "empty"},
+		{"text", []byte("hello, world\n"), "text"},
+		{"binary", []byte{0, 1, 2, 3, 0xff}, "data"},
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00"), "PNG image"},
+		{"webp", []byte("RIFF\x00\x00\x00\x00WEBPVP8 "), "WebP image"},
+		{"gzip", []byte("\x1f\x8b\x08\x00"), "gzip archive"},
+		{"zstd", []byte("\x28\xb5\x2f\xfd\x00"), "zstd archive"},
+		{"tar", tar, "tar archive"},
+		{"mp4", []byte("\x00\x00\x00\x18ftypmp42"), "MP4 video"},
+		{"pe", peHeader(0x80), "Windows executable"},
+		{"pe signature past the head", peHeader(600)[:512], "data"},
+		{"pe without signature", append([]byte("MZ"), make([]byte, 0x40)...), "data"},
+		{"text starting with MZ", []byte("MZ is the start of a DOS header.\n" + strings.Repeat("More text.\n", 8)), "text"},
+		{"short MZ", []byte("MZ"), "text"},
+		{"elf object", elfHeader(1, 1), "ELF object"},
+		{"elf executable", elfHeader(2, 1), "ELF executable"},
+		{"elf pie", elfHeader(3, 3), "ELF executable"},
+		{"elf library", elfHeader(3, 1), "ELF shared object"},
+		{"short elf", []byte("\x7fELF\x02\x01"), "ELF executable"},
+		{"sh script", []byte("#!/bin/sh\necho hi\n"), "sh script"},
+		{"env script", []byte("#!/usr/bin/env -S python3 -u\n"), "python3 script"},
+		{"bare shebang", []byte("#!\n"), "text"},
+	}
+	for _, test := range tests {
+		if got := identifyContent(test.head).name; got != test.want {
+			t.Errorf("%s: identifyContent = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestShebangInterpreter(t *testing.T) {
+	tests := map[string]string{
+		"/bin/bash":                   "bash",
+		" /usr/bin/python3 -u":        "python3",
+		"/usr/bin/env node":           "node",
+		"/usr/bin/env -S FOO=1 ruby":  "ruby",
+		"/usr/bin/env":                "",
+		"":                            "",
+		"/usr/local/bin/fish --login": "fish",
+	}
+	for line, want := range tests {
+		if got := shebangInterpreter(line); got != want {
+			t.Errorf("shebangInterpreter(%q) = %q, want %q", line, got, want)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package gols
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// summaryTopCount is how many of the largest and newest files -f shows.
+const summaryTopCount = 5
+
+// summary accumulates what -f reports about the listed entries.
+type summary struct {
+	ls   *lister
+	root string
+
+	dirs, files, symlinkDirs, symlinkFiles int
+	hidden, executable                     int
+	totalSize                              int64
+
+	extensions map[string]*extensionStats
+	largest    []summaryFile
+	newest     []summaryFile
+}
+
+// extensionStats counts the files with one extension. sample is one of
+// them, whose icon and colour stand for the extension.
+type extensionStats struct {
+	count     int
+	size      int64
+	sample    os.DirEntry
+	sampleDir string
+}
+
+type summaryFile struct {
+	path string
+	info os.FileInfo
+}
+
+// newSummary starts a summary of entries under root, which the paths of
+// the largest and newest files are made relative to.
+func newSummary(ls *lister, root string) *summary {
+	return &summary{ls: ls, root: root, extensions: map[string]*extensionStats{}}
+}
+
+// add counts file, an entry of directory.
+func (s *summary) add(file os.DirEntry, directory string) {
+	if strings.HasPrefix(file.Name(), ".") {
+		s.hidden++
+	}
+
+	if file.Type()&os.ModeSymlink != 0 {
+		linkTarget, err := s.ls.readLink(filepath.Join(directory, file.Name()))
+		if err == nil {
+			targetInfo, err := s.ls.statPath(filepath.Join(directory, linkTarget))
+			if err == nil && targetInfo.IsDir() {
+				s.symlinkDirs++
+			} else {
+				s.symlinkFiles++
+			}
+		}
+		return
+	}
+	if file.IsDir() {
+		s.dirs++
+		return
+	}
+
+	s.files++
+	info, err := file.Info()
+	if err != nil {
+		return
+	}
+	if info.Mode()&0111 != 0 {
+		s.executable++
+	}
+	s.totalSize += info.Size()
+
+	ext := strings.ToLower(filepath.Ext(file.Name()))
+	stats, found := s.extensions[ext]
+	if !found {
+		stats = &extensionStats{sample: file, sampleDir: directory}
+		s.extensions[ext] = stats
+	}
+	stats.count++
+	stats.size += info.Size()
+
+	path := filepath.Join(directory, file.Name())
+	if rel, err := filepath.Rel(s.root, path); err == nil {
+		path = rel
+	}
+	entry := summaryFile{path, info}
+	s.largest = insertTop(s.largest, entry, func(a, b summaryFile) bool { return a.info.Size() > b.info.Size() })
+	s.newest = insertTop(s.newest, entry, func(a, b summaryFile) bool { return a.info.ModTime().After(b.info.ModTime()) })
+}
+
+// insertTop adds entry to list, kept sorted by before and no longer
+// than summaryTopCount.
+func insertTop(list []summaryFile, entry summaryFile, before func(a, b summaryFile) bool) []summaryFile {
+	i := sort.Search(len(list), func(i int) bool { return before(entry, list[i]) })
+	if i >= summaryTopCount {
+		return list
+	}
+	list = append(list, summaryFile{})
+	copy(list[i+1:], list[i:])
+	list[i] = entry
+	if len(list) > summaryTopCount {
+		list = list[:summaryTopCount]
+	}
+	return list
+}
+
+func (s *summary) print(w io.Writer) {
+	fmt.Fprintf(w, "%s %s%d%s\n", s.ls.withIcon(iconDirectory, "Directories:"), blue, s.dirs, reset)
+	fmt.Fprintf(w, "%s %s%d%s\n", s.ls.withIcon(iconOther, "Files:"), red, s.files, reset)
+
+	if s.symlinkDirs > 0 {
+		fmt.Fprintf(w, "%s %s%d%s\n", s.ls.withIcon(iconSymlinkDir, "Symlinked Directories:"), magenta, s.symlinkDirs, reset)
+	}
+	if s.symlinkFiles > 0 {
+		fmt.Fprintf(w, "%s %s%d%s\n", s.ls.withIcon(iconSymlinkFile, "Symlinked Files:"), cyan, s.symlinkFiles, reset)
+	}
+
+	total := s.dirs + s.files + s.symlinkDirs + s.symlinkFiles
+	fmt.Fprintf(w, "TOTAL"+":%s%d%s\n", brightGreen, total, reset)
+
+	fmt.Fprintf(w, "Size: %s%s%s (%d bytes)\n", yellow, formatSize(s.totalSize, true), reset, s.totalSize)
+	fmt.Fprintf(w, "Hidden: %s%d%s  Executable: %s%d%s\n", gray, s.hidden, reset, green, s.executable, reset)
+
+	if len(s.extensions) > 0 {
+		s.printExtensions(w)
+	}
+
+	if len(s.largest) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Largest:")
+		for _, file := range s.largest {
+			fmt.Fprintf(w, "  %s%10s%s  %s\n", yellow, formatSize(file.info.Size(), true), reset, file.path)
+		}
+
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Newest:")
+		for _, file := range s.newest {
+			fmt.Fprintf(w, "  %s%s%s  %s\n", magenta, file.info.ModTime().Format("2006-01-02 15:04"), reset, file.path)
+		}
+	}
+}
+
+// printExtensions prints the number and total size of the files of each
+// extension, largest first. Beyond ten extensions the rest are added up.
+func (s *summary) printExtensions(w io.Writer) {
+	exts := make([]string, 0, len(s.extensions))
+	width := len("(none)")
+	for ext := range s.extensions {
+		exts = append(exts, ext)
+		width = max(width, len(ext))
+	}
+	sort.Slice(exts, func(i, j int) bool {
+		a, b := s.extensions[exts[i]], s.extensions[exts[j]]
+		if a.size != b.size {
+			return a.size > b.size
+		}
+		return exts[i] < exts[j]
+	})
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Extensions:")
+	var other extensionStats
+	for i, ext := range exts {
+		stats := s.extensions[ext]
+		if i >= 10 {
+			other.count += stats.count
+			other.size += stats.size
+			continue
+		}
+
+		label := ext
+		if label == "" {
+			label = "(none)"
+		}
+		icon := " "
+		if s.ls.opts.NoIcons {
+			icon = ""
+		} else if info, err := stats.sample.Info(); err == nil {
+			icon = s.ls.getFileIcon(stats.sample, info.Mode(), stats.sampleDir)
+		}
+		fmt.Fprintf(w, "  %s%s %6d %10s\n", icon, padRight(label, width), stats.count, formatSize(stats.size, true))
+	}
+	if other.count > 0 {
+		icon := "  "
+		if s.ls.opts.NoIcons {
+			icon = ""
+		}
+		fmt.Fprintf(w, "  %s%s %6d %10s\n", icon, padRight("other", width), other.count, formatSize(other.size, true))
+	}
+}
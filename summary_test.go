@@ -0,0 +1,137 @@
+package gols
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestSummaryAdd(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main.go":    "package main\n",
+		"lib.go":     "package lib\n\nfunc f() {}\n",
+		"README.md":  "# gols\n",
+		".env":       "A=1\n",
+		"Makefile":   "all:\n",
+		"tool":       "#!/bin/sh\n",
+		"src/nested": "not counted\n",
+	})
+	if err := os.Chmod(filepath.Join(dir, "tool"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "README.md"), mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("src", filepath.Join(dir, "srclink")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("main.go", filepath.Join(dir, "mainlink")); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newSummary(defaultLister(), dir)
+	for _, file := range files {
+		s.add(file, dir)
+	}
+
+	if s.dirs != 1 || s.files != 6 || s.symlinkDirs != 1 || s.symlinkFiles != 1 {
+		t.Errorf("counted %d dirs, %d files, %d and %d symlinks", s.dirs, s.files, s.symlinkDirs, s.symlinkFiles)
+	}
+	if s.hidden != 1 || s.executable != 1 {
+		t.Errorf("counted %d hidden and %d executable", s.hidden, s.executable)
+	}
+	if s.totalSize != 13+25+7+4+5+10 {
+		t.Errorf("total size = %d", s.totalSize)
+	}
+	if stats := s.extensions[".go"]; stats == nil || stats.count != 2 || stats.size != 38 {
+		t.Errorf(".go extension = %+v", stats)
+	}
+	if none := s.extensions[""]; none == nil || none.count != 2 {
+		t.Errorf("files without an extension = %+v", none)
+	}
+	if s.largest[0].path != "lib.go" || s.newest[0].path != "README.md" {
+		t.Errorf("largest %s, newest %s", s.largest[0].path, s.newest[0].path)
+	}
+	if len(s.largest) != summaryTopCount || len(s.newest) != summaryTopCount {
+		t.Errorf("%d largest and %d newest kept, want %d", len(s.largest), len(s.newest), summaryTopCount)
+	}
+}
+
+func TestInsertTop(t *testing.T) {
+	bySize := func(a, b summaryFile) bool { return a.info.Size() > b.info.Size() }
+	dir := t.TempDir()
+	var list []summaryFile
+	for i, size := range []int{3, 9, 1, 7, 5, 8, 2} {
+		name := filepath.Join(dir, strings.Repeat("f", i+1))
+		if err := os.WriteFile(name, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		list = insertTop(list, summaryFile{name, info}, bySize)
+	}
+
+	var sizes []int64
+	for _, file := range list {
+		sizes = append(sizes, file.info.Size())
+	}
+	if want := []int64{9, 8, 7, 5, 3}; !reflect.DeepEqual(sizes, want) {
+		t.Errorf("kept sizes %v, want %v", sizes, want)
+	}
+}
+
+func TestSummaryHidden(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":       {Data: []byte("package main\n")},
+		".env":          {Data: []byte("A=1\n")},
+		".cache/x":      {Data: []byte("x\n")},
+		"src/lib.go":    {Data: []byte("package lib\n")},
+		"src/.hidden":   {Data: []byte("\n")},
+		"src/.keep":     {},
+		"src/deep/a.go": {Data: []byte("package deep\n")},
+	}
+
+	tests := []struct {
+		name string
+		opts func(*Options)
+		want int
+	}{
+		{name: "hidden left out", want: 2},
+		{name: "hidden shown", opts: func(o *Options) { o.All = true }, want: 2},
+		{name: "tree", opts: func(o *Options) { o.Recursive = true }, want: 4},
+		{name: "tree with hidden", opts: func(o *Options) { o.Recursive, o.All = true, true }, want: 4},
+		{name: "tree to depth 0", opts: func(o *Options) { o.Recursive, o.MaxDepth = true, 0 }, want: 2},
+		{name: "files only", opts: func(o *Options) { o.FilesOnly = true }, want: 2},
+	}
+	for _, test := range tests {
+		opts := DefaultOptions()
+		opts.FS = fsys
+		if test.opts != nil {
+			test.opts(&opts)
+		}
+		ls, err := newLister(opts)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		l, err := ls.newListing(context.Background(), ".")
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if got := l.summary().hidden; got != test.want {
+			t.Errorf("%s: hidden = %d, want %d", test.name, got, test.want)
+		}
+	}
+}
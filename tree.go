@@ -0,0 +1,169 @@
+package gols
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// treeGuides are the pieces a tree is drawn with: the branch to an
+// entry and to the last entry of a directory, and what continues below
+// a directory with more entries to come, or none.
+type treeGuides struct {
+	branch, last, line, blank string
+}
+
+var treeStyles = map[string]treeGuides{
+	"unicode": {"├── ", "└── ", "│   ", "    "},
+	"ascii":   {"|-- ", "`-- ", "|   ", "    "},
+	"rounded": {"├── ", "╰── ", "│   ", "    "},
+	"none":    {"", "", "    ", "    "},
+}
+
+// treeRow is one line of the tree view: an entry of the listing, or
+// with --collapse a chain of directories that ends in one.
+type treeRow struct {
+	index int // of the entry, the last of a chain
+	label string
+	depth int
+}
+
+// treeRows lays the entries of a tree listing out as rows. With
+// --collapse a directory whose only entry is a directory shares its
+// row, as in a/b/c, and what is below moves up a level.
+func (ls *lister) treeRows(entries []Entry) []treeRow {
+	parent := make([]int, len(entries))
+	children := make([]int, len(entries))
+	var ancestors []int
+	for i, entry := range entries {
+		ancestors = append(ancestors[:entry.Depth], i)
+		parent[i] = -1
+		if entry.Depth > 0 {
+			parent[i] = ancestors[entry.Depth-1]
+			children[parent[i]]++
+		}
+	}
+
+	joinsParent := func(i int) bool {
+		p := parent[i]
+		if !ls.opts.Collapse || p == -1 || children[p] != 1 || !entries[i].IsDir() || !entries[p].IsDir() {
+			return false
+		}
+		stats := entries[p].stats
+		return entries[p].Err == nil && (!ls.opts.HiddenCount || stats.hidden == 0)
+	}
+
+	rows := make([]treeRow, 0, len(entries))
+	lift := make([]int, len(entries))
+	for i, entry := range entries {
+		if p := parent[i]; p != -1 {
+			lift[i] = lift[p]
+			if joinsParent(p) {
+				lift[i]++
+			}
+		}
+		if joinsParent(i) {
+			row := &rows[len(rows)-1]
+			row.index = i
+			row.label += "/" + entry.Name
+			continue
+		}
+		rows = append(rows, treeRow{index: i, label: entry.Name, depth: entry.Depth - lift[i]})
+	}
+	return rows
+}
+
+// renderTree draws a tree listing with the guides of --tree-style. Each
+// row is the last of its siblings when no row of the same depth follows
+// before the tree climbs back above it.
+func renderTree(w io.Writer, l *Listing) error {
+	ls := l.lister()
+	rows := ls.treeRows(l.Entries)
+	guides := treeStyles[ls.treeStyle]
+
+	isLast := make([]bool, len(rows))
+	var sibling []bool
+	for i := len(rows) - 1; i >= 0; i-- {
+		depth := rows[i].depth
+		for len(sibling) <= depth {
+			sibling = append(sibling, false)
+		}
+		isLast[i] = !sibling[depth]
+		sibling[depth] = true
+		clear(sibling[depth+1:])
+	}
+
+	// With -l or -s the columns of those listings come first, lined up
+	// across the whole tree, and the lines that continue an entry are
+	// indented past them.
+	var columns []string
+	switch {
+	case l.Options.Long:
+		columns = ls.longPrefixes(l.Entries, l.Options.HumanReadable)
+	case l.Options.Sizes:
+		columns = ls.sizePrefixes(l.Entries, l.Options.HumanReadable)
+	}
+	indent := ""
+	if len(columns) > 0 {
+		indent = strings.Repeat(" ", visibleWidth(columns[0]))
+	}
+
+	prefixes := []string{""}
+	for i, row := range rows {
+		entry := l.Entries[row.index]
+		if columns != nil {
+			fmt.Fprint(w, columns[row.index])
+		}
+		prefix := prefixes[row.depth]
+		if isLast[i] {
+			fmt.Fprint(w, prefix+guides.last)
+		} else {
+			fmt.Fprint(w, prefix+guides.branch)
+		}
+
+		var after string
+		if l.decorate != nil {
+			var before string
+			before, after = l.decorate(entry)
+			fmt.Fprint(w, before)
+		}
+		ls.printFile(w, entry, row.label, true)
+		if entry.Info.Mode()&os.ModeSymlink != 0 {
+			if entry.LinkTarget != "" {
+				fmt.Fprintf(w, " %s==> %s%s", cyan, entry.LinkTarget, reset)
+			} else {
+				fmt.Fprintf(w, " %s==> error%s", red, reset)
+			}
+			if entry.loop {
+				fmt.Fprintf(w, " %s[recursive, not followed]%s", red, reset)
+			}
+		}
+		if entry.stats.unlisted > 0 {
+			fmt.Fprintf(w, " %s(%d entries)%s", gray, entry.stats.unlisted, reset)
+		}
+		if ls.opts.HiddenCount && entry.stats.hidden > 0 {
+			fmt.Fprintf(w, " %s(%d hidden)%s", gray, entry.stats.hidden, reset)
+		}
+		fmt.Fprintln(w, after)
+
+		// Symlinks followed with -L have entries below them too.
+		if isLast[i] {
+			prefix += guides.blank
+		} else {
+			prefix += guides.line
+		}
+		prefixes = append(prefixes[:row.depth+1], prefix)
+
+		if entry.Err != nil {
+			if os.IsPermission(entry.Err) {
+				fmt.Fprintf(w, "%s%sError: Permission denied for %s%s\n", indent, red, entry.Path, reset)
+			} else {
+				fmt.Fprintf(w, "%s%sError reading directory %s: %v%s\n", indent, red, entry.Path, entry.Err, reset)
+			}
+		}
+	}
+
+	writeSummary(w, l)
+	return nil
+}
@@ -0,0 +1,101 @@
+package gols
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRenderTree(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":            {Data: []byte("a")},
+		"cmd/gols/main.go": {Data: []byte("package main\n")},
+		"src/lib.go":       {Data: []byte("package lib\n")},
+		"src/.hidden":      {Data: []byte("\n")},
+		"src/util/x.go":    {Data: []byte("package util\n")},
+	}
+
+	tests := []struct {
+		name string
+		opts func(*Options)
+		want string
+	}{
+		{
+			name: "unicode",
+			want: "" +
+				"├── a.txt\n" +
+				"├── cmd\n" +
+				"│   └── gols\n" +
+				"│       └── main.go\n" +
+				"└── src\n" +
+				"    ├── lib.go\n" +
+				"    └── util\n" +
+				"        └── x.go\n",
+		},
+		{
+			name: "ascii",
+			opts: func(o *Options) { o.TreeStyle = "ascii" },
+			want: "" +
+				"|-- a.txt\n" +
+				"|-- cmd\n" +
+				"|   `-- gols\n" +
+				"|       `-- main.go\n" +
+				"`-- src\n" +
+				"    |-- lib.go\n" +
+				"    `-- util\n" +
+				"        `-- x.go\n",
+		},
+		{
+			name: "none",
+			opts: func(o *Options) { o.TreeStyle = "none" },
+			want: "" +
+				"a.txt\n" +
+				"cmd\n" +
+				"    gols\n" +
+				"        main.go\n" +
+				"src\n" +
+				"    lib.go\n" +
+				"    util\n" +
+				"        x.go\n",
+		},
+		{
+			name: "collapsed",
+			opts: func(o *Options) { o.TreeStyle, o.Collapse = "rounded", true },
+			want: "" +
+				"├── a.txt\n" +
+				"├── cmd/gols\n" +
+				"│   ╰── main.go\n" +
+				"╰── src\n" +
+				"    ├── lib.go\n" +
+				"    ╰── util\n" +
+				"        ╰── x.go\n",
+		},
+		{
+			name: "hidden counted",
+			opts: func(o *Options) { o.MaxDepth, o.HiddenCount = 1, true },
+			want: "" +
+				"├── a.txt\n" +
+				"├── cmd\n" +
+				"│   └── gols\n" +
+				"└── src (1 hidden)\n" +
+				"    ├── lib.go\n" +
+				"    └── util\n",
+		},
+	}
+	for _, test := range tests {
+		opts := DefaultOptions()
+		opts.FS = fsys
+		opts.Recursive = true
+		opts.NoIcons = true
+		if test.opts != nil {
+			test.opts(&opts)
+		}
+		var out bytes.Buffer
+		if _, err := Print(&out, ".", opts); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if got := ansiEscapes.ReplaceAllString(out.String(), ""); got != test.want {
+			t.Errorf("%s: tree =\n%s\nwant\n%s", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,568 @@
+package gols
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+	"unicode"
+	"unicode/utf8"
+	"unsafe"
+)
+
+var tuiSortModes = []string{"name", "size", "time"}
+
+// tuiState is the state of the interactive browser.
+type tuiState struct {
+	ls        *lister
+	tty       io.ReadWriter
+	dir       string
+	all       []os.DirEntry
+	entries   []os.DirEntry
+	cursor    int
+	offset    int
+	sortMode  int
+	filter    string
+	filtering bool
+	preview   bool
+	message   string
+	width     int
+	height    int
+
+	previewKey   string
+	previewLines []string
+}
+
+// Browse runs the interactive browser of gols --tui on directory, with
+// the filters and sort order of opts and on opts.FS when set, reading
+// keys from tty and drawing on it. It returns the path the user chose,
+// if any. When tty is a terminal it is put in raw mode for the duration
+// and redrawn when resized.
+func Browse(tty io.ReadWriter, directory string, opts Options) (string, error) {
+	ls, err := newLister(opts)
+	if err != nil {
+		return "", err
+	}
+	// Each directory is listed on its own, with the name filters.
+	ls.recursive = false
+	return ls.runTUI(tty, directory)
+}
+
+// runTUI runs the full-screen browser on tty and returns the path chosen
+// by the user: the current directory on q, a file on Enter, or nothing
+// when aborted.
+func (ls *lister) runTUI(tty io.ReadWriter, directory string) (string, error) {
+	resize := make(chan os.Signal, 1)
+	if f, ok := tty.(*os.File); ok {
+		var saved *syscall.Termios
+		var err error
+		if controlErr := ttyControl(f, func(fd uintptr) { saved, err = makeRaw(fd) }); controlErr != nil {
+			err = controlErr
+		}
+		if err != nil {
+			return "", err
+		}
+		defer ttyControl(f, func(fd uintptr) { setTermios(fd, saved) })
+
+		signal.Notify(resize, syscall.SIGWINCH)
+		defer signal.Stop(resize)
+	}
+
+	fmt.Fprint(tty, "\033[?1049h\033[?25l")
+	defer fmt.Fprint(tty, "\033[?25h\033[?1049l")
+
+	dir := filepath.Clean(directory)
+	if ls.fsys == nil {
+		var err error
+		if dir, err = filepath.Abs(directory); err != nil {
+			return "", err
+		}
+	}
+	st := &tuiState{ls: ls, tty: tty, dir: dir, preview: true}
+	switch {
+	case ls.opts.SortBySize:
+		st.sortMode = 1
+	case ls.opts.SortByTime:
+		st.sortMode = 2
+	}
+	if err := st.load(""); err != nil {
+		return "", err
+	}
+
+	keys, stop := readKeys(tty)
+	defer stop()
+
+	for {
+		st.draw()
+		select {
+		case <-resize:
+		case key, ok := <-keys:
+			if !ok {
+				return "", nil
+			}
+			if selected, done := st.handle(key); done {
+				return selected, nil
+			}
+		}
+	}
+}
+
+// readKeys sends what is read from tty, a key press at a time, until
+// stop is called or reading fails. When tty is an *os.File, stop also
+// interrupts the pending read and waits for it, so that nothing reads
+// from tty once Browse has returned.
+func readKeys(tty io.Reader) (<-chan string, func()) {
+	keys := make(chan string)
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		defer close(keys)
+		buf := make([]byte, 32)
+		for {
+			n, err := tty.Read(buf)
+			if err != nil {
+				return
+			}
+			select {
+			case keys <- string(buf[:n]):
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	stop := func() {
+		close(done)
+		f, ok := tty.(*os.File)
+		if !ok || f.SetReadDeadline(time.Now()) != nil {
+			return
+		}
+		<-finished
+		f.SetReadDeadline(time.Time{})
+	}
+	return keys, stop
+}
+
+// ttyControl runs fn with the descriptor of f. Unlike f.Fd, it leaves f
+// in non-blocking mode, so the read deadline readKeys sets still works.
+func ttyControl(f *os.File, fn func(fd uintptr)) error {
+	conn, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+	return conn.Control(fn)
+}
+
+func makeRaw(fd uintptr) (*syscall.Termios, error) {
+	saved := &syscall.Termios{}
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlReadTermios, uintptr(unsafe.Pointer(saved))); errno != 0 {
+		return nil, fmt.Errorf("failed to read terminal state: %v", errno)
+	}
+
+	raw := *saved
+	raw.Iflag &^= syscall.ICRNL | syscall.IXON | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR
+	raw.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := setTermios(fd, &raw); err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+func setTermios(fd uintptr, t *syscall.Termios) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlWriteTermios, uintptr(unsafe.Pointer(t))); errno != 0 {
+		return fmt.Errorf("failed to set terminal state: %v", errno)
+	}
+	return nil
+}
+
+func terminalSize(fd uintptr) (int, int) {
+	ws := &winsize{}
+	_, _, err := syscall.Syscall(syscall.SYS_IOCTL, fd, uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(ws)))
+	if err != 0 || ws.Col == 0 || ws.Row == 0 {
+		return 80, 24
+	}
+	return int(ws.Col), int(ws.Row)
+}
+
+// load lists the current directory the way gols would, with the
+// filters of the call, and places the cursor on selectName when it is
+// present, so going up keeps the directory we came from highlighted.
+func (st *tuiState) load(selectName string) error {
+	files, _, _, err := st.ls.readEntries(st.dir)
+	if err != nil && err != errNoFiles {
+		return err
+	}
+	st.all = files
+	st.sortEntries()
+	st.applyFilter()
+
+	st.cursor, st.offset = 0, 0
+	for i, file := range st.entries {
+		if file.Name() == selectName {
+			st.cursor = i
+		}
+	}
+	return nil
+}
+
+func (st *tuiState) sortEntries() {
+	switch tuiSortModes[st.sortMode] {
+	case "name":
+		sort.SliceStable(st.all, func(i, j int) bool { return st.all[i].Name() < st.all[j].Name() })
+	case "size":
+		sort.SliceStable(st.all, func(i, j int) bool {
+			info1, _ := st.all[i].Info()
+			info2, _ := st.all[j].Info()
+			return info1 != nil && info2 != nil && info1.Size() < info2.Size()
+		})
+	case "time":
+		sort.SliceStable(st.all, func(i, j int) bool {
+			info1, _ := st.all[i].Info()
+			info2, _ := st.all[j].Info()
+			return info1 != nil && info2 != nil && info1.ModTime().Before(info2.ModTime())
+		})
+	}
+}
+
+// applyFilter keeps the entries whose name contains the filter, ignoring
+// case, and keeps the cursor in range.
+func (st *tuiState) applyFilter() {
+	st.entries = st.entries[:0]
+	needle := strings.ToLower(st.filter)
+	for _, file := range st.all {
+		if strings.Contains(strings.ToLower(file.Name()), needle) {
+			st.entries = append(st.entries, file)
+		}
+	}
+	st.cursor = max(0, min(st.cursor, len(st.entries)-1))
+}
+
+func (st *tuiState) selected() os.DirEntry {
+	if len(st.entries) == 0 {
+		return nil
+	}
+	return st.entries[st.cursor]
+}
+
+// selectedIsDir also treats symlinks to directories and archives as
+// directories.
+func (st *tuiState) selectedIsDir() bool {
+	file := st.selected()
+	if file == nil {
+		return false
+	}
+	if file.IsDir() {
+		return true
+	}
+	full := filepath.Join(st.dir, file.Name())
+	if err := st.ls.openArchive(full); err != nil {
+		return false
+	}
+	info, err := st.ls.statPath(full)
+	return err == nil && info.IsDir()
+}
+
+func (st *tuiState) chdir(dir, selectName string) {
+	previous := st.dir
+	st.dir = dir
+	st.filter, st.filtering = "", false
+	if err := st.load(selectName); err != nil {
+		st.message = err.Error()
+		st.dir = previous
+		st.load("")
+	}
+}
+
+// handle applies a key press and reports whether the browser is done,
+// together with the path to print.
+func (st *tuiState) handle(key string) (string, bool) {
+	st.message = ""
+	page := max(1, st.height-3)
+
+	if st.filtering {
+		switch key {
+		case "\r":
+			st.filtering = false
+			return "", false
+		case "\x1b":
+			st.filter, st.filtering = "", false
+			st.applyFilter()
+			return "", false
+		case "\x7f", "\b":
+			if st.filter != "" {
+				_, size := utf8.DecodeLastRuneInString(st.filter)
+				st.filter = st.filter[:len(st.filter)-size]
+				st.applyFilter()
+			}
+			return "", false
+		}
+		if r, _ := utf8.DecodeRuneInString(key); r != utf8.RuneError && unicode.IsPrint(r) {
+			st.filter += key
+			st.cursor = 0
+			st.applyFilter()
+			return "", false
+		}
+	}
+
+	switch key {
+	case "q":
+		return st.dir, true
+	case "\x03":
+		return "", true
+	case "\x1b":
+		if st.filter == "" {
+			return "", true
+		}
+		st.filter = ""
+		st.applyFilter()
+	case "j", "\x1b[B", "\x0e":
+		st.cursor++
+	case "k", "\x1b[A", "\x10":
+		st.cursor--
+	case "g", "\x1b[H", "\x1b[1~":
+		st.cursor = 0
+	case "G", "\x1b[F", "\x1b[4~":
+		st.cursor = len(st.entries) - 1
+	case "\x1b[6~", "\x04":
+		st.cursor += page
+	case "\x1b[5~", "\x15":
+		st.cursor -= page
+	case "l", "\x1b[C", "\r":
+		if st.selectedIsDir() {
+			st.chdir(filepath.Join(st.dir, st.selected().Name()), "")
+		} else if key == "\r" && st.selected() != nil {
+			return filepath.Join(st.dir, st.selected().Name()), true
+		}
+	case "h", "\x1b[D", "\x7f", "\b":
+		st.chdir(filepath.Dir(st.dir), filepath.Base(st.dir))
+	case "~":
+		if home, err := os.UserHomeDir(); err == nil && st.ls.fsys == nil {
+			st.chdir(home, "")
+		}
+	case ".":
+		st.ls.showHidden = !st.ls.showHidden
+		name := ""
+		if file := st.selected(); file != nil {
+			name = file.Name()
+		}
+		st.load(name)
+	case "s":
+		st.sortMode = (st.sortMode + 1) % len(tuiSortModes)
+		st.sortEntries()
+		st.applyFilter()
+	case "/":
+		st.filtering = true
+	case "p":
+		st.preview = !st.preview
+	}
+
+	st.cursor = max(0, min(st.cursor, len(st.entries)-1))
+	return "", false
+}
+
+func (st *tuiState) draw() {
+	st.width, st.height = 80, 24
+	if f, ok := st.tty.(*os.File); ok {
+		ttyControl(f, func(fd uintptr) { st.width, st.height = terminalSize(fd) })
+	}
+	rows := max(1, st.height-2)
+
+	if st.cursor < st.offset {
+		st.offset = st.cursor
+	}
+	if st.cursor >= st.offset+rows {
+		st.offset = st.cursor - rows + 1
+	}
+
+	listWidth := st.width
+	if st.preview {
+		listWidth = st.width / 2
+		st.loadPreview(rows, st.width-listWidth-3)
+	}
+
+	var b strings.Builder
+	b.WriteString("\033[H")
+
+	status := fmt.Sprintf(" sort:%s", tuiSortModes[st.sortMode])
+	if st.ls.showHidden {
+		status += " hidden"
+	}
+	header := truncateRunes(st.dir, st.width-utf8.RuneCountInString(status)-1)
+	b.WriteString(blue + header + reset + gray + status + reset + "\033[K\r\n")
+
+	for row := 0; row < rows; row++ {
+		i := st.offset + row
+		if i < len(st.entries) {
+			b.WriteString(st.formatRow(st.entries[i], listWidth, i == st.cursor))
+		}
+		b.WriteString("\033[K")
+
+		if st.preview {
+			fmt.Fprintf(&b, "\033[%d;%dH%s│%s ", row+2, listWidth+1, gray, reset)
+			if row < len(st.previewLines) {
+				b.WriteString(st.previewLines[row])
+			}
+			b.WriteString("\033[K")
+		}
+		b.WriteString("\r\n")
+	}
+
+	switch {
+	case st.filtering:
+		b.WriteString("/" + st.filter + "█")
+	case st.message != "":
+		b.WriteString(red + st.message + reset)
+	case st.filter != "":
+		b.WriteString(gray + "filter: " + st.filter + "  (Esc clears)" + reset)
+	default:
+		b.WriteString(gray + "j/k move  l enter  h up  / filter  . hidden  s sort  p preview  q quit" + reset)
+	}
+	b.WriteString("\033[K\033[J")
+
+	io.WriteString(st.tty, b.String())
+}
+
+// formatRow renders an entry like the permission listing: colored
+// permissions, the icon from getFileIcon and the name, reversed when it
+// is under the cursor.
+func (st *tuiState) formatRow(file os.DirEntry, width int, current bool) string {
+	info, err := file.Info()
+	if err != nil {
+		return red + file.Name() + reset
+	}
+
+	name := truncateRunes(file.Name(), max(1, width-15))
+	if file.IsDir() {
+		name = blue + name
+	}
+	if current {
+		name = "\033[7m" + name
+	}
+	return st.ls.formatPermissions(file, info.Mode(), st.dir) + " " + st.ls.getFileIcon(file, info.Mode(), st.dir) + name + reset
+}
+
+// loadPreview fills the preview pane for the entry under the cursor: the
+// contents of a directory or the first lines of a text file.
+func (st *tuiState) loadPreview(rows, width int) {
+	file := st.selected()
+	if file == nil {
+		st.previewKey, st.previewLines = "", nil
+		return
+	}
+	full := filepath.Join(st.dir, file.Name())
+	key := fmt.Sprintf("%s\x00%d\x00%d\x00%t", full, rows, width, st.ls.showHidden)
+	if key == st.previewKey {
+		return
+	}
+	st.previewKey = key
+	st.previewLines = nil
+
+	if err := st.ls.openArchive(full); err != nil {
+		st.previewLines = []string{red + err.Error() + reset}
+		return
+	}
+	info, err := st.ls.statPath(full)
+	if err != nil {
+		st.previewLines = []string{red + err.Error() + reset}
+		return
+	}
+
+	if info.IsDir() {
+		files, _, _, err := st.ls.readEntries(full)
+		if err != nil && err != errNoFiles {
+			st.previewLines = []string{red + err.Error() + reset}
+			return
+		}
+		for _, child := range files {
+			if len(st.previewLines) == rows {
+				break
+			}
+			childInfo, err := child.Info()
+			if err != nil {
+				continue
+			}
+			name := truncateRunes(child.Name(), max(1, width-3))
+			if child.IsDir() {
+				name = blue + name + reset
+			}
+			st.previewLines = append(st.previewLines, st.ls.getFileIcon(child, childInfo.Mode(), full)+name+reset)
+		}
+		return
+	}
+
+	if !info.Mode().IsRegular() {
+		return
+	}
+	if _, _, inArchive := st.ls.archiveFor(full); inArchive {
+		st.previewLines = []string{gray + fmt.Sprintf("archived file, %s", formatSize(info.Size(), true)) + reset}
+		return
+	}
+	f, err := st.ls.sysOpen(full)
+	if err != nil {
+		st.previewLines = []string{red + err.Error() + reset}
+		return
+	}
+	defer f.Close()
+
+	buf := make([]byte, 64*1024)
+	n, _ := f.Read(buf)
+	data := buf[:n]
+	if !isText(data) {
+		st.previewLines = []string{gray + fmt.Sprintf("binary file, %s", formatSize(info.Size(), true)) + reset}
+		return
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if len(st.previewLines) == rows {
+			break
+		}
+		line = strings.ReplaceAll(strings.TrimRight(line, "\r"), "\t", "    ")
+		line = strings.Map(func(r rune) rune {
+			if unicode.IsControl(r) {
+				return -1
+			}
+			return r
+		}, line)
+		st.previewLines = append(st.previewLines, truncateRunes(line, width))
+	}
+}
+
+// isText reports whether data looks like UTF-8 text. A multi-byte rune
+// cut off at the end of the buffer is allowed.
+func isText(data []byte) bool {
+	if len(data) > utf8.UTFMax {
+		for i := len(data) - 1; i > len(data)-utf8.UTFMax; i-- {
+			if utf8.RuneStart(data[i]) {
+				if !utf8.FullRune(data[i:]) {
+					data = data[:i]
+				}
+				break
+			}
+		}
+	}
+	for _, c := range data {
+		if c == 0 {
+			return false
+		}
+	}
+	return utf8.Valid(data)
+}
+
+func truncateRunes(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:n-1]) + "…"
+}
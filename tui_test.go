@@ -0,0 +1,109 @@
+package gols
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// fakeTTY reads keys from a string, one call per key, and collects what
+// is drawn.
+type fakeTTY struct {
+	keys []string
+	bytes.Buffer
+}
+
+func (t *fakeTTY) Read(p []byte) (int, error) {
+	if len(t.keys) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, t.keys[0])
+	t.keys = t.keys[1:]
+	return n, nil
+}
+
+func TestBrowse(t *testing.T) {
+	fsys := fstest.MapFS{
+		"src/main.go":   {Data: []byte("package main\n")},
+		"src/notes.txt": {Data: []byte("notes\n")},
+		"src/.hidden":   {Data: []byte("\n")},
+		"README.md":     {Data: []byte("# gols\n")},
+	}
+
+	tests := []struct {
+		name string
+		dir  string
+		opts func(*Options)
+		keys []string
+		want string
+	}{
+		{name: "enter and pick", dir: ".", keys: []string{"j", "l", "\r"}, want: "src/main.go"},
+		{name: "quit", dir: ".", keys: []string{"q"}, want: "."},
+		{name: "abort", dir: "src", keys: []string{"\x03"}, want: ""},
+		{name: "up", dir: "src", keys: []string{"h", "k", "\r"}, want: "README.md"},
+		{name: "bottom", dir: "src", keys: []string{"G", "\r"}, want: "src/notes.txt"},
+		{name: "filtered", dir: "src", opts: func(o *Options) { o.Extensions = []string{"txt"} }, keys: []string{"\r"}, want: "src/notes.txt"},
+		{name: "filter typed", dir: "src", keys: []string{"/", "t", "x", "\r", "\r"}, want: "src/notes.txt"},
+		{name: "filter cleared", dir: "src", keys: []string{"/", "t", "x", "\x1b", "\r"}, want: "src/main.go"},
+		{name: "hidden", dir: "src", opts: func(o *Options) { o.All = true }, keys: []string{"\r"}, want: "src/.hidden"},
+		{name: "hidden toggled", dir: "src", keys: []string{".", "g", "\r"}, want: "src/.hidden"},
+		{name: "sorted by size", dir: "src", opts: func(o *Options) { o.SortBySize = true }, keys: []string{"\r"}, want: "src/notes.txt"},
+		{name: "size sort toggled", dir: "src", keys: []string{"s", "G", "\r"}, want: "src/main.go"},
+		{name: "end of input", dir: ".", keys: nil, want: ""},
+	}
+	for _, test := range tests {
+		opts := DefaultOptions()
+		opts.FS = fsys
+		if test.opts != nil {
+			test.opts(&opts)
+		}
+		tty := &fakeTTY{keys: test.keys}
+		got, err := Browse(tty, test.dir, opts)
+		if err != nil || got != test.want {
+			t.Errorf("%s: Browse = %q, %v, want %q", test.name, got, err, test.want)
+		}
+		if !strings.Contains(tty.String(), "\033[?1049l") {
+			t.Errorf("%s: the screen was not restored", test.name)
+		}
+	}
+}
+
+func TestIsText(t *testing.T) {
+	tests := []struct {
+		data string
+		want bool
+	}{
+		{"plain text\n", true},
+		{"", true},
+		{"caf\xc3\xa9", true},
+		{"cut short caf\xc3", true},
+		{"nul\x00byte", false},
+		{"\xff\xfe invalid", false},
+	}
+	for _, test := range tests {
+		if got := isText([]byte(test.data)); got != test.want {
+			t.Errorf("isText(%q) = %v, want %v", test.data, got, test.want)
+		}
+	}
+}
+
+func TestTruncateRunes(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"exact", 5, "exact"},
+		{"longer name", 6, "longe…"},
+		{"héllo wörld", 4, "hél…"},
+		{"anything", 0, ""},
+	}
+	for _, test := range tests {
+		if got := truncateRunes(test.s, test.n); got != test.want {
+			t.Errorf("truncateRunes(%q, %d) = %q, want %q", test.s, test.n, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package gols
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// watchHighlight is how long created and modified entries stay
+	// highlighted after a change.
+	watchHighlight = 2 * time.Second
+
+	// watchPollInterval is how often the tree is rescanned when no
+	// change notifier is available, and how often highlights expire.
+	watchPollInterval = time.Second
+)
+
+// changeNotifier tells the watch loop that something changed in one of
+// the directories it was asked to watch. On Linux it is backed by
+// inotify; elsewhere gols falls back to polling.
+type changeNotifier interface {
+	add(dir string) error
+	events() <-chan struct{}
+	close()
+}
+
+// watchEntry is the metadata compared between two scans.
+type watchEntry struct {
+	size    int64
+	modTime time.Time
+	mode    os.FileMode
+}
+
+// Watch prints the listing of directory with opts to w and redraws it
+// whenever entries change, like gols --watch, until ctx is done.
+func Watch(ctx context.Context, w io.Writer, directory string, opts Options) error {
+	opts.FS = nil
+	ls, err := newLister(opts)
+	if err != nil {
+		return err
+	}
+	// The printers join directory and name the same way as the keys.
+	ls.watchChanged = map[string]time.Time{}
+	return ls.runWatch(ctx, w, directory)
+}
+
+// runWatch prints the listing of directory and redraws it whenever
+// entries are created, removed, renamed or modified, until ctx is done.
+func (ls *lister) runWatch(ctx context.Context, w io.Writer, directory string) error {
+	if _, err := os.Stat(directory); err != nil {
+		return err
+	}
+
+	notifier, err := newChangeNotifier()
+	if err != nil {
+		notifier = nil
+	} else {
+		defer notifier.close()
+	}
+
+	var events <-chan struct{}
+	if notifier != nil {
+		events = notifier.events()
+	}
+
+	previous, dirs := ls.takeWatchSnapshot(directory)
+	watchDirectories(notifier, dirs)
+
+	var removed []string
+	var removedUntil time.Time
+	redraw := func() {
+		fmt.Fprint(w, "\033[H\033[2J")
+		fmt.Fprintf(w, "%sWatching %s  %s%s\n\n", gray, directory, time.Now().Format("15:04:05"), reset)
+		if _, err := ls.listDirectory(w, directory); err != nil {
+			fmt.Fprintln(w, red+err.Error()+reset)
+		}
+		fmt.Fprintln(w)
+		if len(removed) > 0 && time.Now().Before(removedUntil) {
+			fmt.Fprintf(w, "\n%sRemoved: %s%s\n", red, strings.Join(removed, ", "), reset)
+		}
+	}
+	redraw()
+
+	ticker := time.NewTicker(watchPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-events:
+			// Let a burst of events settle before rescanning.
+			time.Sleep(50 * time.Millisecond)
+		case <-ticker.C:
+			if notifier != nil && len(ls.watchChanged) == 0 && len(removed) == 0 {
+				continue
+			}
+		}
+
+		ls.forget()
+		current, dirs := ls.takeWatchSnapshot(directory)
+		changed, gone := diffWatchSnapshots(previous, current)
+		previous = current
+
+		now := time.Now()
+		for _, path := range changed {
+			ls.watchChanged[path] = now.Add(watchHighlight)
+		}
+		if len(gone) > 0 {
+			removed = removed[:0]
+			for _, path := range gone {
+				if rel, err := filepath.Rel(directory, path); err == nil {
+					removed = append(removed, rel)
+				}
+			}
+			removedUntil = now.Add(watchHighlight)
+		}
+
+		expired := false
+		for path, until := range ls.watchChanged {
+			if now.After(until) {
+				delete(ls.watchChanged, path)
+				expired = true
+			}
+		}
+		if len(removed) > 0 && now.After(removedUntil) {
+			removed = nil
+			expired = true
+		}
+
+		if len(changed) > 0 || len(gone) > 0 || expired {
+			watchDirectories(notifier, dirs)
+			redraw()
+		}
+	}
+}
+
+func watchDirectories(notifier changeNotifier, dirs []string) {
+	if notifier == nil {
+		return
+	}
+	for _, dir := range dirs {
+		notifier.add(dir)
+	}
+}
+
+// watchHighlighted reports whether name in directory changed recently.
+func (ls *lister) watchHighlighted(directory, name string) bool {
+	until, found := ls.watchChanged[filepath.Join(directory, name)]
+	return found && time.Now().Before(until)
+}
+
+// takeWatchSnapshot records the entries shown by the current view: the
+// directory itself, or its whole tree in -r mode, and returns them with
+// the directories to watch.
+func (ls *lister) takeWatchSnapshot(directory string) (map[string]watchEntry, []string) {
+	snapshot := map[string]watchEntry{}
+
+	info, err := os.Stat(directory)
+	if err != nil {
+		return snapshot, nil
+	}
+	if !info.IsDir() {
+		snapshot[directory] = watchEntry{info.Size(), info.ModTime(), info.Mode()}
+		return snapshot, []string{filepath.Dir(directory)}
+	}
+
+	// The name filters are left to the listing, so that a file renamed
+	// into or out of them still shows up as a change.
+	walk := ls.withoutIncludes()
+	if !ls.recursive {
+		walk.opts.MaxDepth = 0
+	}
+	dirs := []string{directory}
+	walk.walkEntries(context.Background(), directory, func(entry Entry) error {
+		snapshot[entry.Path] = watchEntry{entry.Info.Size(), entry.Info.ModTime(), entry.Info.Mode()}
+		if entry.entered {
+			dirs = append(dirs, entry.Path)
+		}
+		return nil
+	})
+	return snapshot, dirs
+}
+
+// diffWatchSnapshots returns the created or modified paths and the
+// removed ones. A rename shows up as one of each.
+func diffWatchSnapshots(previous, current map[string]watchEntry) ([]string, []string) {
+	var changed, gone []string
+	for path, entry := range current {
+		if old, found := previous[path]; !found || old != entry {
+			changed = append(changed, path)
+		}
+	}
+	for path := range previous {
+		if _, found := current[path]; !found {
+			gone = append(gone, path)
+		}
+	}
+	sort.Strings(gone)
+	return changed, gone
+}
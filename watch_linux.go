@@ -0,0 +1,93 @@
+package gols
+
+import (
+	"encoding/binary"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// inotifyNotifier watches directories with inotify(7). The descriptor is
+// non-blocking and read through an os.File, so that closing the file
+// wakes the reader up.
+type inotifyNotifier struct {
+	fd      int
+	file    *os.File
+	mu      sync.Mutex
+	watched map[string]int32
+	ch      chan struct{}
+}
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MODIFY |
+	syscall.IN_ATTRIB | syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_FROM |
+	syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF
+
+func newChangeNotifier() (changeNotifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &inotifyNotifier{
+		fd:      fd,
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		watched: map[string]int32{},
+		ch:      make(chan struct{}, 1),
+	}
+	go n.read()
+	return n, nil
+}
+
+func (n *inotifyNotifier) add(dir string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if _, found := n.watched[dir]; found {
+		return nil
+	}
+	wd, err := syscall.InotifyAddWatch(n.fd, dir, inotifyMask)
+	if err != nil {
+		return err
+	}
+	n.watched[dir] = int32(wd)
+	return nil
+}
+
+func (n *inotifyNotifier) events() <-chan struct{} { return n.ch }
+
+func (n *inotifyNotifier) close() { n.file.Close() }
+
+// read forwards events as wake-ups and forgets watches the kernel
+// dropped, so a directory that is removed and created again is watched
+// anew.
+func (n *inotifyNotifier) read() {
+	buf := make([]byte, 64*1024)
+	for {
+		count, err := n.file.Read(buf)
+		if err != nil || count <= 0 {
+			return
+		}
+
+		for pos := 0; pos+syscall.SizeofInotifyEvent <= count; {
+			wd := int32(binary.NativeEndian.Uint32(buf[pos:]))
+			mask := binary.NativeEndian.Uint32(buf[pos+4:])
+			length := int(binary.NativeEndian.Uint32(buf[pos+12:]))
+			pos += syscall.SizeofInotifyEvent + length
+
+			if mask&syscall.IN_IGNORED != 0 {
+				n.mu.Lock()
+				for dir, watched := range n.watched {
+					if watched == wd {
+						delete(n.watched, dir)
+					}
+				}
+				n.mu.Unlock()
+			}
+		}
+
+		select {
+		case n.ch <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package gols
+
+import "errors"
+
+// newChangeNotifier has no implementation outside Linux; the watch loop
+// then falls back to polling.
+func newChangeNotifier() (changeNotifier, error) {
+	return nil, errors.New("change notifications are not supported on this system")
+}
@@ -0,0 +1,164 @@
+package gols
+
+import (
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestDiffWatchSnapshots(t *testing.T) {
+	mtime := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	file := watchEntry{size: 1, modTime: mtime, mode: 0o644}
+	previous := map[string]watchEntry{"same": file, "grown": file, "touched": file, "chmod": file, "gone": file, "renamed": file}
+
+	grown, touched, chmod := file, file, file
+	grown.size = 2
+	touched.modTime = mtime.Add(time.Second)
+	chmod.mode = 0o600
+	current := map[string]watchEntry{"same": file, "grown": grown, "touched": touched, "chmod": chmod, "new": file, "renamed-to": file}
+
+	changed, gone := diffWatchSnapshots(previous, current)
+	sort.Strings(changed)
+	if want := []string{"chmod", "grown", "new", "renamed-to", "touched"}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("changed = %v, want %v", changed, want)
+	}
+	if want := []string{"gone", "renamed"}; !reflect.DeepEqual(gone, want) {
+		t.Errorf("gone = %v, want %v", gone, want)
+	}
+
+	if changed, gone := diffWatchSnapshots(previous, previous); changed != nil || gone != nil {
+		t.Errorf("an unchanged snapshot differs: %v, %v", changed, gone)
+	}
+}
+
+func TestTakeWatchSnapshot(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"a.go":     "a",
+		"b.txt":    "b",
+		".hidden":  "h",
+		"sub/c.go": "c",
+		"sub/d/e":  "e",
+		"other/f":  "f",
+	})
+
+	tests := []struct {
+		name  string
+		path  string
+		opts  func(*Options)
+		paths []string
+		dirs  []string
+	}{
+		{
+			name:  "listing",
+			path:  ".",
+			paths: []string{"a.go", "b.txt", "other", "sub"},
+			dirs:  []string{"."},
+		},
+		{
+			name:  "tree",
+			path:  ".",
+			opts:  func(o *Options) { o.Recursive = true },
+			paths: []string{"a.go", "b.txt", "other", "other/f", "sub", "sub/c.go", "sub/d", "sub/d/e"},
+			dirs:  []string{".", "other", "sub", "sub/d"},
+		},
+		{
+			name:  "filtered names are still watched",
+			path:  ".",
+			opts:  func(o *Options) { o.Extensions, o.All = []string{"go"}, true },
+			paths: []string{".hidden", "a.go", "b.txt", "other", "sub"},
+			dirs:  []string{"."},
+		},
+		{
+			name:  "file",
+			path:  "a.go",
+			paths: []string{"a.go"},
+			dirs:  []string{"."},
+		},
+	}
+	for _, test := range tests {
+		opts := DefaultOptions()
+		if test.opts != nil {
+			test.opts(&opts)
+		}
+		ls, err := newLister(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		snapshot, dirs := ls.takeWatchSnapshot(filepath.Join(root, test.path))
+
+		rel := func(p string) string {
+			r, _ := filepath.Rel(root, p)
+			return filepath.ToSlash(r)
+		}
+		var paths []string
+		for p := range snapshot {
+			paths = append(paths, rel(p))
+		}
+		sort.Strings(paths)
+		var relDirs []string
+		for _, dir := range dirs {
+			relDirs = append(relDirs, rel(dir))
+		}
+		sort.Strings(relDirs)
+		if !reflect.DeepEqual(paths, test.paths) || !reflect.DeepEqual(relDirs, test.dirs) {
+			t.Errorf("%s: snapshot %v of %v, want %v of %v", test.name, paths, relDirs, test.paths, test.dirs)
+		}
+	}
+
+	if snapshot, dirs := defaultLister().takeWatchSnapshot(filepath.Join(root, "missing")); len(snapshot) != 0 || dirs != nil {
+		t.Errorf("snapshot of a missing path = %v, %v", snapshot, dirs)
+	}
+}
+
+func TestChangeNotifier(t *testing.T) {
+	notifier, err := newChangeNotifier()
+	if err != nil {
+		t.Skip(err)
+	}
+	defer notifier.close()
+
+	dir := t.TempDir()
+	if err := notifier.add(dir); err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, dir, map[string]string{"new": "x"})
+	select {
+	case <-notifier.events():
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event for a new file")
+	}
+}
+
+// TestChangeNotifierClose checks that closing a notifier stops the
+// goroutine reading its events.
+func TestChangeNotifierClose(t *testing.T) {
+	before := runtime.NumGoroutine()
+	notifier, err := newChangeNotifier()
+	if err != nil {
+		t.Skip(err)
+	}
+	dir := t.TempDir()
+	if err := notifier.add(dir); err != nil {
+		t.Fatal(err)
+	}
+	// After an event the reader is waiting for the next one.
+	writeFiles(t, dir, map[string]string{"new": "x"})
+	select {
+	case <-notifier.events():
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event for a new file")
+	}
+	time.Sleep(10 * time.Millisecond)
+	notifier.close()
+
+	for deadline := time.Now().Add(5 * time.Second); runtime.NumGoroutine() > before; {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines after close, want %d", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}